@@ -0,0 +1,352 @@
+package gorbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Operations recorded in Decision.Op.
+const (
+	OpIsGranted  = "IsGranted"
+	OpAnyGranted = "AnyGranted"
+	OpAllGranted = "AllGranted"
+	// OpBatchGranted records one BatchGranted call, see Decision.Results.
+	OpBatchGranted = "BatchGranted"
+)
+
+// Decision records one authorization decision.
+type Decision[R, P comparable] struct {
+	Time        time.Time `json:"time"`
+	Op          string    `json:"op"`
+	Subject     string    `json:"subject,omitempty"`
+	Roles       []R       `json:"roles"`
+	Permissions []P       `json:"permissions"`
+	Granted     bool      `json:"granted"`
+	// Results holds the outcome of every permission of an OpBatchGranted
+	// decision, whose Granted reports whether all of them were granted.
+	Results []bool `json:"results,omitempty"`
+}
+
+// DecisionLogger receives authorization decisions.
+//
+// LogDecision is called synchronously on the checking goroutine; wrap slow
+// sinks with NewAsyncDecisionLogger.
+type DecisionLogger[R, P comparable] interface {
+	LogDecision(ctx context.Context, d Decision[R, P])
+}
+
+// DecisionLoggerFunc adapts a function to DecisionLogger.
+type DecisionLoggerFunc[R, P comparable] func(ctx context.Context, d Decision[R, P])
+
+// LogDecision calls f(ctx, d).
+func (f DecisionLoggerFunc[R, P]) LogDecision(ctx context.Context, d Decision[R, P]) {
+	f(ctx, d)
+}
+
+type decisionLogConfig struct {
+	subject    func(context.Context) string
+	sampleRate float64
+}
+
+// DecisionLogOption customizes how decisions are logged.
+type DecisionLogOption func(*decisionLogConfig)
+
+// WithDecisionSubject extracts the subject (user, service account, ...)
+// recorded with every decision from the checking context. By default the
+// subject attached by WithSubject is recorded.
+func WithDecisionSubject(subject func(context.Context) string) DecisionLogOption {
+	return func(cfg *decisionLogConfig) {
+		cfg.subject = subject
+	}
+}
+
+// WithDecisionSampleRate logs only the given fraction of decisions,
+// between 0 and 1. Every decision is logged by default.
+func WithDecisionSampleRate(rate float64) DecisionLogOption {
+	return func(cfg *decisionLogConfig) {
+		cfg.sampleRate = min(max(rate, 0), 1)
+	}
+}
+
+type decisionHook[R, P comparable] struct {
+	logger DecisionLogger[R, P]
+	cfg    decisionLogConfig
+}
+
+func newDecisionHook[R, P comparable](logger DecisionLogger[R, P], opts []DecisionLogOption) *decisionHook[R, P] {
+	if logger == nil {
+		return nil
+	}
+	cfg := decisionLogConfig{sampleRate: 1}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+	return &decisionHook[R, P]{logger: logger, cfg: cfg}
+}
+
+func (h *decisionHook[R, P]) log(ctx context.Context, op string, roles []R, perms []Permission[P], granted bool) {
+	h.logResults(ctx, op, roles, perms, granted, nil)
+}
+
+// logBatch logs one OpBatchGranted decision for the outcomes `results` of
+// `perms`.
+func (h *decisionHook[R, P]) logBatch(ctx context.Context, roles []R, perms []Permission[P], results []bool) {
+	h.logResults(ctx, OpBatchGranted, roles, perms, !slices.Contains(results, false), results)
+}
+
+func (h *decisionHook[R, P]) logResults(ctx context.Context, op string, roles []R, perms []Permission[P], granted bool, results []bool) {
+	if h == nil || ctx.Value(unloggedKey{}) != nil {
+		return
+	}
+	if h.cfg.sampleRate < 1 && rand.Float64() >= h.cfg.sampleRate {
+		return
+	}
+	d := Decision[R, P]{
+		Time:        time.Now(),
+		Op:          op,
+		Roles:       append([]R(nil), roles...),
+		Permissions: make([]P, 0, len(perms)),
+		Granted:     granted,
+	}
+	for i, p := range perms {
+		if p == nil {
+			continue
+		}
+		d.Permissions = append(d.Permissions, p.ID())
+		if results != nil {
+			d.Results = append(d.Results, results[i])
+		}
+	}
+	if h.cfg.subject != nil {
+		d.Subject = h.cfg.subject(ctx)
+	} else {
+		d.Subject = subjectString(ctx)
+	}
+	h.logger.LogDecision(ctx, d)
+}
+
+// decisionLogged is implemented by RBAC instances with a decision hook, so
+// that AnyGranted and AllGranted log one aggregated decision instead of one
+// per IsGranted call.
+type decisionLogged[R, P comparable] interface {
+	logDecision(ctx context.Context, op string, roles []R, perms []Permission[P], granted bool)
+}
+
+// unloggedKey marks a context whose IsGranted decisions are part of a
+// decision AnyGranted or AllGranted logs as a whole.
+type unloggedKey struct{}
+
+// withoutDecisionLog returns a context in which decision hooks log nothing.
+func withoutDecisionLog(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unloggedKey{}, true)
+}
+
+// SetDecisionLogger registers `logger` to receive every decision made by
+// IsGranted, AnyGranted, AllGranted and BatchGranted. Passing nil disables logging.
+func (rbac *StdRBACOf[R, P]) SetDecisionLogger(logger DecisionLogger[R, P], opts ...DecisionLogOption) {
+	rbac.mutex.Lock()
+	rbac.decisions = newDecisionHook(logger, opts)
+	rbac.mutex.Unlock()
+}
+
+func (rbac *StdRBACOf[R, P]) logDecision(ctx context.Context, op string, roles []R, perms []Permission[P], granted bool) {
+	rbac.mutex.RLock()
+	hook := rbac.decisions
+	rbac.mutex.RUnlock()
+	hook.log(ctx, op, roles, perms, granted)
+}
+
+// LoggedRBACOf decorates any RBAC implementation with a decision logger.
+type LoggedRBACOf[R, P comparable] struct {
+	RBACOf[R, P]
+	hook *decisionHook[R, P]
+}
+
+// LoggedRBAC is a LoggedRBACOf where role IDs and permission IDs share the type T.
+type LoggedRBAC[T comparable] = LoggedRBACOf[T, T]
+
+// NewLogged wraps `inner` so that every decision is sent to `logger`.
+func NewLogged[R, P comparable](inner RBACOf[R, P], logger DecisionLogger[R, P],
+	opts ...DecisionLogOption) *LoggedRBACOf[R, P] {
+	return &LoggedRBACOf[R, P]{
+		RBACOf: inner,
+		hook:   newDecisionHook(logger, opts),
+	}
+}
+
+// IsGranted tests if the role `id` has permission `p` and logs the decision.
+func (r *LoggedRBACOf[R, P]) IsGranted(ctx context.Context, id R, p Permission[P]) bool {
+	ok := r.RBACOf.IsGranted(ctx, id, p)
+	r.hook.log(ctx, OpIsGranted, []R{id}, []Permission[P]{p}, ok)
+	return ok
+}
+
+// Check tests if the role `id` has permission `p` and logs the decision
+// unless an error occurred.
+func (r *LoggedRBACOf[R, P]) Check(ctx context.Context, id R, p Permission[P]) (bool, error) {
+	ok, err := Check(ctx, r.RBACOf, id, p)
+	if err == nil {
+		r.hook.log(ctx, OpIsGranted, []R{id}, []Permission[P]{p}, ok)
+	}
+	return ok, err
+}
+
+// BatchGranted tests every permission against the role set `roles`, see
+// gorbac.BatchGranted, and logs one aggregated decision.
+func (r *LoggedRBACOf[R, P]) BatchGranted(ctx context.Context, roles []R, permissions ...Permission[P]) []bool {
+	result := BatchGranted(ctx, r.RBACOf, roles, permissions...)
+	r.hook.logBatch(ctx, roles, permissions, result)
+	return result
+}
+
+func (r *LoggedRBACOf[R, P]) logDecision(ctx context.Context, op string, roles []R, perms []Permission[P], granted bool) {
+	r.hook.log(ctx, op, roles, perms, granted)
+}
+
+// AsyncDecisionLogger delivers decisions to another logger from a buffered
+// background goroutine. Decisions are dropped instead of blocking the
+// checking goroutine when the buffer is full.
+type AsyncDecisionLogger[R, P comparable] struct {
+	next    DecisionLogger[R, P]
+	queue   chan asyncDecision[R, P]
+	done    chan struct{}
+	once    sync.Once
+	mutex   sync.RWMutex
+	closed  bool
+	dropped atomic.Uint64
+}
+
+type asyncDecision[R, P comparable] struct {
+	ctx context.Context
+	d   Decision[R, P]
+}
+
+// NewAsyncDecisionLogger starts delivering decisions to `next` with a
+// buffer of `size` decisions. Call Close to flush and stop it.
+func NewAsyncDecisionLogger[R, P comparable](next DecisionLogger[R, P], size int) *AsyncDecisionLogger[R, P] {
+	l := &AsyncDecisionLogger[R, P]{
+		next:  next,
+		queue: make(chan asyncDecision[R, P], max(size, 0)),
+		done:  make(chan struct{}),
+	}
+	go func() {
+		defer close(l.done)
+		for item := range l.queue {
+			l.next.LogDecision(item.ctx, item.d)
+		}
+	}()
+	return l
+}
+
+// LogDecision queues the decision without blocking.
+func (l *AsyncDecisionLogger[R, P]) LogDecision(ctx context.Context, d Decision[R, P]) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if l.closed {
+		l.dropped.Add(1)
+		return
+	}
+	select {
+	case l.queue <- asyncDecision[R, P]{ctx: context.WithoutCancel(ctx), d: d}:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of decisions dropped so far.
+func (l *AsyncDecisionLogger[R, P]) Dropped() uint64 {
+	return l.dropped.Load()
+}
+
+// Close delivers the queued decisions and stops the background goroutine.
+func (l *AsyncDecisionLogger[R, P]) Close() error {
+	l.once.Do(func() {
+		l.mutex.Lock()
+		l.closed = true
+		close(l.queue)
+		l.mutex.Unlock()
+	})
+	<-l.done
+	return nil
+}
+
+// JSONLinesDecisionSink writes each decision as one JSON object per line.
+type JSONLinesDecisionSink[R, P comparable] struct {
+	mutex  sync.Mutex
+	closer io.Closer
+	enc    *json.Encoder
+	err    error
+}
+
+// NewJSONLinesDecisionSink writes decisions to `w`.
+func NewJSONLinesDecisionSink[R, P comparable](w io.Writer) *JSONLinesDecisionSink[R, P] {
+	return &JSONLinesDecisionSink[R, P]{enc: json.NewEncoder(w)}
+}
+
+// OpenJSONLinesDecisionFile appends decisions to the file `name`, creating it
+// if necessary.
+func OpenJSONLinesDecisionFile[R, P comparable](name string) (*JSONLinesDecisionSink[R, P], error) {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	sink := NewJSONLinesDecisionSink[R, P](f)
+	sink.closer = f
+	return sink, nil
+}
+
+// LogDecision writes the decision. The first write error is kept and
+// reported by Err; later decisions are discarded.
+func (s *JSONLinesDecisionSink[R, P]) LogDecision(_ context.Context, d Decision[R, P]) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.err != nil {
+		return
+	}
+	s.err = s.enc.Encode(d)
+}
+
+// Err returns the first write error.
+func (s *JSONLinesDecisionSink[R, P]) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.err
+}
+
+// Close closes the file opened by OpenJSONLinesDecisionFile.
+func (s *JSONLinesDecisionSink[R, P]) Close() error {
+	if s.closer == nil {
+		return s.Err()
+	}
+	if err := s.closer.Close(); err != nil {
+		return err
+	}
+	return s.Err()
+}
+
+// ReadDecisions decodes the decisions written by a JSONLinesDecisionSink.
+func ReadDecisions[R, P comparable](r io.Reader) ([]Decision[R, P], error) {
+	dec := json.NewDecoder(r)
+	var decisions []Decision[R, P]
+	for {
+		var d Decision[R, P]
+		if err := dec.Decode(&d); err != nil {
+			if errors.Is(err, io.EOF) {
+				return decisions, nil
+			}
+			return nil, err
+		}
+		decisions = append(decisions, d)
+	}
+}
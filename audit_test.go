@@ -0,0 +1,106 @@
+package gorbac
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecisionLogger(t *testing.T) {
+	ctx := WithSubject(context.Background(), "bob")
+	rbac := New[string]()
+	rA := NewRole("role-a")
+	assert(t, rA.Assign(ctx, pA))
+	assert(t, rbac.Add(ctx, rA))
+
+	var decisions []Decision[string, string]
+	logger := DecisionLoggerFunc[string, string](func(_ context.Context, d Decision[string, string]) {
+		decisions = append(decisions, d)
+	})
+	subject := func(ctx context.Context) string {
+		s, _ := SubjectFromContext[string](ctx)
+		return "user:" + s
+	}
+	rbac.SetDecisionLogger(logger, WithDecisionSubject(subject))
+	rbac.IsGranted(ctx, "role-a", pA)
+	AnyGranted(ctx, rbac, []string{"role-a"}, pB, pA)
+	AllGranted(ctx, rbac, []string{"role-a"}, pA, pB)
+	rbac.BatchGranted(ctx, []string{"role-a"}, pA, pB)
+
+	expected := []struct {
+		op      string
+		perms   int
+		granted bool
+	}{
+		{OpIsGranted, 1, true},
+		{OpAnyGranted, 2, true},
+		{OpAllGranted, 2, false},
+		{OpBatchGranted, 2, false},
+	}
+	if len(decisions) != len(expected) {
+		t.Fatalf("%d decisions expected, but %d got", len(expected), len(decisions))
+	}
+	for i, e := range expected {
+		d := decisions[i]
+		if d.Op != e.op || len(d.Permissions) != e.perms || d.Granted != e.granted || d.Subject != "user:bob" {
+			t.Fatalf("unexpected decision %+v", d)
+		}
+	}
+	if r := decisions[3].Results; len(r) != 2 || !r[0] || r[1] {
+		t.Fatalf("unexpected batch results %v", r)
+	}
+
+	decisions = nil
+	rbac.SetDecisionLogger(logger, WithDecisionSampleRate(0))
+	rbac.IsGranted(ctx, "role-a", pA)
+	if len(decisions) != 0 {
+		t.Fatal("no decision should be sampled")
+	}
+
+	logged := NewLogged[string, string](New[string](), logger)
+	AllGranted(ctx, logged, []string{"role-a"}, pA)
+	logged.IsGranted(ctx, "role-a", pA)
+	BatchGranted(ctx, logged, []string{"role-a"}, pA, nil)
+	if len(decisions) != 3 || decisions[0].Op != OpAllGranted || decisions[1].Op != OpIsGranted ||
+		decisions[2].Op != OpBatchGranted || len(decisions[2].Results) != 1 {
+		t.Fatalf("unexpected decisions %+v", decisions)
+	}
+}
+
+func TestAsyncJSONLinesDecisionSink(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	sink := NewJSONLinesDecisionSink[string, string](&buf)
+	async := NewAsyncDecisionLogger[string, string](sink, 16)
+	rbac := New[string]()
+	rbac.SetDecisionLogger(async)
+	rbac.IsGranted(ctx, "role-a", pA)
+	rbac.IsGranted(ctx, "role-b", pB)
+	assert(t, async.Close())
+	assert(t, sink.Err())
+
+	decisions, err := ReadDecisions[string, string](&buf)
+	assert(t, err)
+	if len(decisions) != 2 {
+		t.Fatalf("2 decisions expected, got %d", len(decisions))
+	}
+	for i, role := range []string{"role-a", "role-b"} {
+		if d := decisions[i]; d.Roles[0] != role || d.Granted {
+			t.Fatalf("unexpected decision %+v", d)
+		}
+	}
+	if _, err := ReadDecisions[string, string](strings.NewReader("{}\n{")); err == nil {
+		t.Fatal("a truncated log should be reported")
+	}
+	async.LogDecision(ctx, Decision[string, string]{})
+	if async.Dropped() != 1 {
+		t.Fatal("decisions after Close should be dropped")
+	}
+
+	file, err := OpenJSONLinesDecisionFile[string, string](filepath.Join(t.TempDir(), "decisions.jsonl"))
+	assert(t, err)
+	file.LogDecision(ctx, Decision[string, string]{Op: OpIsGranted})
+	assert(t, file.Close())
+}
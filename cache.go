@@ -0,0 +1,310 @@
+package gorbac
+
+import (
+	"container/list"
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultCacheSize is the number of decisions kept by CachedRBACOf unless
+// WithCacheSize says otherwise.
+const DefaultCacheSize = 4096
+
+type cacheConfig struct {
+	ttl             time.Duration
+	size            int
+	instrumentation Instrumentation
+}
+
+// CacheOption customizes CachedRBACOf construction.
+type CacheOption func(*cacheConfig)
+
+// WithCacheTTL expires cached decisions and parent lookups after `ttl`.
+// A non-positive `ttl` keeps entries until they are invalidated or evicted.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.ttl = ttl
+	}
+}
+
+// WithCacheSize bounds the number of cached decisions and parent lookups.
+// The least recently used decisions are evicted first.
+func WithCacheSize(size int) CacheOption {
+	return func(cfg *cacheConfig) {
+		if size > 0 {
+			cfg.size = size
+		}
+	}
+}
+
+type decisionKey[R comparable] struct {
+	role R
+	perm any
+}
+
+type decisionEntry[R comparable] struct {
+	key     decisionKey[R]
+	granted bool
+	expires time.Time
+	// closure holds the role and its ancestors at the time of the decision
+	closure map[R]struct{}
+}
+
+type parentsEntry[R comparable] struct {
+	parents []R
+	err     error
+	expires time.Time
+}
+
+// CachedRBACOf decorates any RBAC implementation with a decision cache.
+//
+// IsGranted decisions and GetParents lookups are cached. Mutations routed
+// through the wrapper invalidate exactly the decisions of the mutated role and
+// its descendants. Changes applied behind the wrapper, e.g. `Role.Assign` or a
+// shared storage updated by another process, must be signalled through
+// Invalidate or InvalidateAll.
+type CachedRBACOf[R, P comparable] struct {
+	inner RBACOf[R, P]
+	ttl   time.Duration
+	size  int
+	now   func() time.Time
+	inst  Instrumentation
+
+	mutex     sync.Mutex
+	gen       uint64
+	decisions map[decisionKey[R]]*list.Element
+	lru       *list.List
+	parents   map[R]parentsEntry[R]
+}
+
+// CachedRBAC is a CachedRBACOf where role IDs and permission IDs share the type T.
+type CachedRBAC[T comparable] = CachedRBACOf[T, T]
+
+// NewCached wraps `inner` with a decision cache.
+func NewCached[R, P comparable](inner RBACOf[R, P], opts ...CacheOption) *CachedRBACOf[R, P] {
+	cfg := &cacheConfig{size: DefaultCacheSize}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	return &CachedRBACOf[R, P]{
+		inner:     inner,
+		ttl:       cfg.ttl,
+		size:      cfg.size,
+		now:       time.Now,
+		inst:      cfg.instrumentation,
+		decisions: make(map[decisionKey[R]]*list.Element),
+		lru:       list.New(),
+		parents:   make(map[R]parentsEntry[R]),
+	}
+}
+
+// Unwrap returns the decorated RBAC.
+func (c *CachedRBACOf[R, P]) Unwrap() RBACOf[R, P] {
+	return c.inner
+}
+
+// Add a role `r` and invalidate the decisions depending on it.
+func (c *CachedRBACOf[R, P]) Add(ctx context.Context, r RoleOf[R, P]) error {
+	defer c.Invalidate(r.ID())
+	return c.inner.Add(ctx, r)
+}
+
+// Remove the role by `id` and invalidate the decisions depending on it.
+func (c *CachedRBACOf[R, P]) Remove(ctx context.Context, id R) error {
+	defer c.Invalidate(id)
+	return c.inner.Remove(ctx, id)
+}
+
+// Get returns the role by `id`.
+func (c *CachedRBACOf[R, P]) Get(ctx context.Context, id R) (RoleOf[R, P], error) {
+	return c.inner.Get(ctx, id)
+}
+
+// RoleIDs returns all role IDs.
+func (c *CachedRBACOf[R, P]) RoleIDs(ctx context.Context) []R {
+	return c.inner.RoleIDs(ctx)
+}
+
+// SetParents bind `parents` to the role `id` and invalidate the decisions
+// depending on it.
+func (c *CachedRBACOf[R, P]) SetParents(ctx context.Context, id R, parents ...R) error {
+	defer c.Invalidate(id)
+	return c.inner.SetParents(ctx, id, parents...)
+}
+
+// RemoveParents unbind `parents` from the role `id` and invalidate the
+// decisions depending on it.
+func (c *CachedRBACOf[R, P]) RemoveParents(ctx context.Context, id R, parents ...R) error {
+	defer c.Invalidate(id)
+	return c.inner.RemoveParents(ctx, id, parents...)
+}
+
+// GetParents return `parents` of the role `id`, from the cache if possible.
+func (c *CachedRBACOf[R, P]) GetParents(ctx context.Context, id R) ([]R, error) {
+	c.mutex.Lock()
+	if e, ok := c.parents[id]; ok && !c.expired(e.expires) {
+		c.mutex.Unlock()
+		return append([]R(nil), e.parents...), e.err
+	}
+	gen := c.gen
+	c.mutex.Unlock()
+
+	parents, err := c.inner.GetParents(ctx, id)
+
+	c.mutex.Lock()
+	if gen == c.gen {
+		if len(c.parents) >= c.size {
+			c.parents = make(map[R]parentsEntry[R])
+		}
+		c.parents[id] = parentsEntry[R]{
+			parents: append([]R(nil), parents...),
+			err:     err,
+			expires: c.expiry(),
+		}
+	}
+	c.mutex.Unlock()
+	return parents, err
+}
+
+// IsGranted tests if the role `id` has permission `p`, from the cache if possible.
+func (c *CachedRBACOf[R, P]) IsGranted(ctx context.Context, id R, p Permission[P]) bool {
+	ok, _ := c.Check(ctx, id, p)
+	return ok
+}
+
+// Check tests if the role `id` has permission `p`, from the cache if
+// possible. Errors of the decorated RBAC are returned and never cached.
+func (c *CachedRBACOf[R, P]) Check(ctx context.Context, id R, p Permission[P]) (bool, error) {
+	if p == nil || !reflect.TypeOf(p).Comparable() {
+		return Check(ctx, c.inner, id, p)
+	}
+	key := decisionKey[R]{role: id, perm: p}
+
+	c.mutex.Lock()
+	if el, ok := c.decisions[key]; ok {
+		e := el.Value.(*decisionEntry[R])
+		if !c.expired(e.expires) {
+			c.lru.MoveToFront(el)
+			c.mutex.Unlock()
+			c.observe(ctx, true)
+			return e.granted, nil
+		}
+		c.remove(el)
+	}
+	gen := c.gen
+	c.mutex.Unlock()
+	c.observe(ctx, false)
+
+	granted, err := Check(ctx, c.inner, id, p)
+	if err != nil {
+		return false, err
+	}
+	closure := c.closure(ctx, id)
+
+	c.mutex.Lock()
+	// skip caching when anything was invalidated meanwhile
+	if gen == c.gen {
+		if el, ok := c.decisions[key]; ok {
+			c.remove(el)
+		}
+		c.decisions[key] = c.lru.PushFront(&decisionEntry[R]{
+			key:     key,
+			granted: granted,
+			expires: c.expiry(),
+			closure: closure,
+		})
+		for c.lru.Len() > c.size {
+			c.remove(c.lru.Back())
+		}
+	}
+	c.mutex.Unlock()
+	return granted, nil
+}
+
+// Invalidate drops the cached parents of the roles `ids` and every decision
+// whose role inherits from any of them.
+func (c *CachedRBACOf[R, P]) Invalidate(ids ...R) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.gen++
+	for _, id := range ids {
+		delete(c.parents, id)
+		// children of a removed role lose it as a parent
+		for rid, e := range c.parents {
+			for _, parent := range e.parents {
+				if parent == id {
+					delete(c.parents, rid)
+					break
+				}
+			}
+		}
+	}
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		e := el.Value.(*decisionEntry[R])
+		for _, id := range ids {
+			if _, ok := e.closure[id]; ok {
+				c.remove(el)
+				break
+			}
+		}
+		el = next
+	}
+}
+
+// InvalidateAll drops every cached decision and parent lookup.
+func (c *CachedRBACOf[R, P]) InvalidateAll() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.gen++
+	c.decisions = make(map[decisionKey[R]]*list.Element)
+	c.lru.Init()
+	c.parents = make(map[R]parentsEntry[R])
+}
+
+// closure collects the role `id` and its ancestors through the parent cache.
+func (c *CachedRBACOf[R, P]) closure(ctx context.Context, id R) map[R]struct{} {
+	seen := make(map[R]struct{}, 8)
+	stack := []R{id}
+	for len(stack) > 0 {
+		rid := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := seen[rid]; ok {
+			continue
+		}
+		seen[rid] = empty
+		parents, err := c.GetParents(ctx, rid)
+		if err != nil {
+			continue
+		}
+		stack = append(stack, parents...)
+	}
+	return seen
+}
+
+func (c *CachedRBACOf[R, P]) observe(ctx context.Context, hit bool) {
+	if c.inst != nil {
+		c.inst.ObserveCache(ctx, hit)
+	}
+}
+
+func (c *CachedRBACOf[R, P]) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*decisionEntry[R])
+	delete(c.decisions, e.key)
+}
+
+func (c *CachedRBACOf[R, P]) expiry() time.Time {
+	if c.ttl <= 0 {
+		return time.Time{}
+	}
+	return c.now().Add(c.ttl)
+}
+
+func (c *CachedRBACOf[R, P]) expired(expires time.Time) bool {
+	return !expires.IsZero() && !c.now().Before(expires)
+}
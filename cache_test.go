@@ -0,0 +1,92 @@
+package gorbac
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type countingRBAC struct {
+	RBAC[string]
+	granted int
+}
+
+func (r *countingRBAC) IsGranted(ctx context.Context, id string, p Permission[string]) bool {
+	r.granted++
+	return r.RBAC.IsGranted(ctx, id, p)
+}
+
+func TestCachedRBAC(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingRBAC{RBAC: New[string]()}
+	rbac := NewCached[string, string](inner)
+	rA, rB, rC := NewRole("role-a"), NewRole("role-b"), NewRole("role-c")
+	assert(t, rA.Assign(ctx, pA))
+	assert(t, rB.Assign(ctx, pB))
+	assert(t, rC.Assign(ctx, pC))
+	assert(t, rbac.Add(ctx, rA))
+	assert(t, rbac.Add(ctx, rB))
+	assert(t, rbac.Add(ctx, rC))
+	assert(t, rbac.SetParents(ctx, "role-a", "role-b"))
+
+	if !rbac.IsGranted(ctx, "role-a", pB) || !rbac.IsGranted(ctx, "role-a", pB) {
+		t.Fatalf("role-a should have %s which inherits from role-b", pB)
+	}
+	if !rbac.IsGranted(ctx, "role-c", pC) {
+		t.Fatalf("role-c should have %s", pC)
+	}
+	if inner.granted != 2 {
+		t.Fatalf("2 uncached decisions expected, but %d got", inner.granted)
+	}
+
+	// role-c is unrelated to role-b, so only role-a is invalidated
+	assert(t, rB.Revoke(ctx, pB))
+	rbac.Invalidate("role-b")
+	if rbac.IsGranted(ctx, "role-a", pB) {
+		t.Fatalf("role-a should not have %s after revoking", pB)
+	}
+	rbac.IsGranted(ctx, "role-c", pC)
+	if inner.granted != 3 {
+		t.Fatalf("3 uncached decisions expected, but %d got", inner.granted)
+	}
+
+	assert(t, rbac.Remove(ctx, "role-b"))
+	if parents, err := rbac.GetParents(ctx, "role-a"); err != nil {
+		t.Fatal(err)
+	} else if len(parents) != 0 {
+		t.Fatal("removed role-b should not be a parent of role-a")
+	}
+
+	rbac.InvalidateAll()
+	rbac.IsGranted(ctx, "role-c", pC)
+	if inner.granted != 4 {
+		t.Fatalf("4 uncached decisions expected, but %d got", inner.granted)
+	}
+}
+
+func TestCachedRBACBounds(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingRBAC{RBAC: New[string]()}
+	rbac := NewCached[string, string](inner, WithCacheSize(1), WithCacheTTL(time.Minute))
+	now := time.Now()
+	rbac.now = func() time.Time { return now }
+	rA := NewRole("role-a")
+	assert(t, rA.Assign(ctx, pA))
+	assert(t, rbac.Add(ctx, rA))
+
+	rbac.IsGranted(ctx, "role-a", pA)
+	rbac.IsGranted(ctx, "role-a", pA)
+	if inner.granted != 1 {
+		t.Fatalf("1 uncached decision expected, but %d got", inner.granted)
+	}
+	now = now.Add(time.Minute)
+	rbac.IsGranted(ctx, "role-a", pA)
+	if inner.granted != 2 {
+		t.Fatalf("expired decision should be recomputed, %d got", inner.granted)
+	}
+	rbac.IsGranted(ctx, "role-a", pB)
+	rbac.IsGranted(ctx, "role-a", pA)
+	if inner.granted != 4 {
+		t.Fatalf("evicted decision should be recomputed, %d got", inner.granted)
+	}
+}
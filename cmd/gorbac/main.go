@@ -0,0 +1,653 @@
+// Command gorbac works with policy files.
+//
+// Policies are read with policy.Load: either a combined JSON document, the
+// split layout of examples/persistence given as "roles.json,inher.json" or a
+// text policy file ending with ".rbac".
+//
+//	gorbac validate -policy FILE
+//	gorbac check    -policy FILE [-sep SEP] ROLE PERMISSION
+//	gorbac perms    -policy FILE ROLE
+//	gorbac explain  -policy FILE [-sep SEP] [-all] ROLE PERMISSION
+//	gorbac diff     OLD NEW
+//	gorbac fmt      [-json] FILE
+//	gorbac render   -schema FILE [-dialect DIALECT] [-bindings JSON] EXPR
+//	gorbac test     FILE...
+//	gorbac lint     -policy FILE [-schema FILE]
+//	gorbac graph    -policy FILE [-format dot|mermaid] [-perms none|direct|effective]
+//	                [-role ROLE -permission PERMISSION [-sep SEP]]
+//	gorbac recommend -policy FILE -log FILE [-since DATE] [-until DATE]
+//	                [-narrow SUFFIX] [-o FILE]
+//
+// validate, check, diff, test and lint exit with status 1 when the policy is
+// invalid, the permission is denied, the policies differ, a test case fails
+// or a lint warning is found; usage and load errors exit with status 2.
+// Test files are described in the policytest package.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fy0/gorbac/v3"
+	"github.com/fy0/gorbac/v3/filter"
+	"github.com/fy0/gorbac/v3/policy"
+	"github.com/fy0/gorbac/v3/policytest"
+	"github.com/fy0/gorbac/v3/rbaclint"
+	"github.com/fy0/gorbac/v3/rbacrecommend"
+)
+
+const (
+	exitOK    = 0
+	exitFail  = 1
+	exitUsage = 2
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, args []string, stdout, stderr io.Writer) int
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"validate": {"validate -policy FILE", runValidate},
+		"check":    {"check -policy FILE [-sep SEP] ROLE PERMISSION", runCheck},
+		"perms":    {"perms -policy FILE ROLE", runPerms},
+		"explain":  {"explain -policy FILE [-sep SEP] [-all] ROLE PERMISSION", runExplain},
+		"diff":     {"diff OLD NEW", runDiff},
+		"fmt":      {"fmt [-json] FILE", runFmt},
+		"render":   {"render -schema FILE [-dialect DIALECT] [-bindings JSON] EXPR", runRender},
+		"test":     {"test FILE...", runTest},
+		"lint":     {"lint -policy FILE [-schema FILE]", runLint},
+		"recommend": {"recommend -policy FILE -log FILE [-since DATE] [-until DATE] [-narrow SUFFIX] [-o FILE]",
+			runRecommend},
+		"graph": {"graph -policy FILE [-format dot|mermaid] [-perms none|direct|effective] " +
+			"[-role ROLE -permission PERMISSION [-sep SEP]]", runGraph},
+	}
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "gorbac: unknown command %q\n", args[0])
+		usage(stderr)
+		return exitUsage
+	}
+	return cmd.run(ctx, args[1:], stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "\tgorbac %s\n", commands[name].usage)
+	}
+}
+
+// flagSet returns a FlagSet reporting errors to `stderr`.
+func flagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: gorbac %s\n", commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags and checks the number of positional arguments.
+func parse(fs *flag.FlagSet, args []string, nargs int) bool {
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+	if fs.NArg() != nargs {
+		fs.Usage()
+		return false
+	}
+	return true
+}
+
+// loadRBAC loads and builds the policy given by -policy, rejecting
+// inheritance cycles, which would make every check recurse forever.
+func loadRBAC(ctx context.Context, spec string, stderr io.Writer) (*gorbac.StdRBAC[string], bool) {
+	rbac, ok := loadPolicy(ctx, spec, stderr)
+	if !ok {
+		return nil, false
+	}
+	if err := gorbac.InherCircle(ctx, rbac); err != nil {
+		fmt.Fprintf(stderr, "gorbac: %s: inheritance %v\n", spec, err)
+		return nil, false
+	}
+	return rbac, true
+}
+
+// loadPolicy loads and builds the policy given by -policy, cycles included.
+func loadPolicy(ctx context.Context, spec string, stderr io.Writer) (*gorbac.StdRBAC[string], bool) {
+	if spec == "" {
+		fmt.Fprintln(stderr, "gorbac: -policy is required")
+		return nil, false
+	}
+	doc, err := policy.Load(spec)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return nil, false
+	}
+	rbac, err := doc.Build(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return nil, false
+	}
+	return rbac, true
+}
+
+// loadSchema reads a filter.SchemaSpec file.
+func loadSchema(name string, stderr io.Writer) (filter.Schema, bool) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return filter.Schema{}, false
+	}
+	schema, err := filter.SchemaFromJSON(data)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %s: %v\n", name, err)
+		return filter.Schema{}, false
+	}
+	return schema, true
+}
+
+// permission builds the requested permission, layered when `sep` is set.
+func permission(id, sep string) gorbac.Permission[string] {
+	if sep != "" {
+		return gorbac.NewLayerPermission(id, sep)
+	}
+	return gorbac.NewPermission(id)
+}
+
+func runValidate(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flagSet("validate", stderr)
+	spec := fs.String("policy", "", "policy file")
+	if !parse(fs, args, 0) {
+		return exitUsage
+	}
+	if *spec == "" {
+		fmt.Fprintln(stderr, "gorbac: -policy is required")
+		return exitUsage
+	}
+	doc, err := policy.Load(*spec)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitUsage
+	}
+	issues := doc.Validate(ctx)
+	for _, issue := range issues {
+		fmt.Fprintln(stdout, issue.Error())
+	}
+	if len(issues) > 0 {
+		return exitFail
+	}
+	fmt.Fprintf(stdout, "ok: %d roles\n", len(doc.Roles))
+	return exitOK
+}
+
+func runCheck(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flagSet("check", stderr)
+	spec := fs.String("policy", "", "policy file")
+	sep := fs.String("sep", "", "check a layered permission split by `SEP`")
+	if !parse(fs, args, 2) {
+		return exitUsage
+	}
+	rbac, ok := loadRBAC(ctx, *spec, stderr)
+	if !ok {
+		return exitUsage
+	}
+	role, perm := fs.Arg(0), fs.Arg(1)
+	if _, err := rbac.Get(ctx, role); err != nil {
+		fmt.Fprintf(stderr, "gorbac: %s: %v\n", role, err)
+		return exitUsage
+	}
+	if !rbac.IsGranted(ctx, role, permission(perm, *sep)) {
+		fmt.Fprintln(stdout, "denied")
+		return exitFail
+	}
+	fmt.Fprintln(stdout, "granted")
+	return exitOK
+}
+
+func runPerms(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flagSet("perms", stderr)
+	spec := fs.String("policy", "", "policy file")
+	if !parse(fs, args, 1) {
+		return exitUsage
+	}
+	rbac, ok := loadRBAC(ctx, *spec, stderr)
+	if !ok {
+		return exitUsage
+	}
+	perms, err := gorbac.EffectivePermissions(ctx, rbac, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %s: %v\n", fs.Arg(0), err)
+		return exitUsage
+	}
+	lines := make([]string, 0, len(perms))
+	for _, p := range perms {
+		record, err := gorbac.RecordOf(p)
+		if err != nil {
+			lines = append(lines, p.ID())
+			continue
+		}
+		lines = append(lines, policy.FormatRecord(record))
+	}
+	slices.Sort(lines)
+	for _, line := range lines {
+		fmt.Fprintln(stdout, line)
+	}
+	return exitOK
+}
+
+func runExplain(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flagSet("explain", stderr)
+	spec := fs.String("policy", "", "policy file")
+	sep := fs.String("sep", "", "explain a layered permission split by `SEP`")
+	all := fs.Bool("all", false, "list every grant path and the minimal sets of parent edges cutting them")
+	if !parse(fs, args, 2) {
+		return exitUsage
+	}
+	rbac, ok := loadRBAC(ctx, *spec, stderr)
+	if !ok {
+		return exitUsage
+	}
+	role, p := fs.Arg(0), permission(fs.Arg(1), *sep)
+	if _, err := rbac.Get(ctx, role); err != nil {
+		fmt.Fprintf(stderr, "gorbac: %s: %v\n", role, err)
+		return exitUsage
+	}
+	if *all {
+		return explainAll(ctx, rbac, role, p, stdout, stderr)
+	}
+	path, ok := gorbac.GrantPath(ctx, rbac, role, p)
+	if !ok {
+		fmt.Fprintf(stdout, "denied: no role inherited by %s holds %s\n", role, p.ID())
+		return exitFail
+	}
+	holder, err := rbac.Get(ctx, path[len(path)-1])
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitUsage
+	}
+	var matched []string
+	for _, held := range holder.Permissions(ctx) {
+		if held.Match(p) {
+			matched = append(matched, held.ID())
+		}
+	}
+	slices.Sort(matched)
+	fmt.Fprintf(stdout, "granted: %s\n", strings.Join(path, " -> "))
+	if len(matched) > 0 {
+		fmt.Fprintf(stdout, "%s holds %s\n", holder.ID(), strings.Join(matched, ", "))
+	}
+	return exitOK
+}
+
+// explainAll prints every grant path and the minimal cut sets.
+func explainAll(ctx context.Context, rbac gorbac.RBAC[string], role string, p gorbac.Permission[string],
+	stdout, stderr io.Writer) int {
+	paths, err := gorbac.GrantPaths(ctx, rbac, role, p)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitUsage
+	}
+	if len(paths) == 0 {
+		fmt.Fprintf(stdout, "denied: no role inherited by %s holds %s\n", role, p.ID())
+		return exitFail
+	}
+	fmt.Fprintln(stdout, "paths:")
+	for _, path := range paths {
+		fmt.Fprintf(stdout, "\t%s\n", strings.Join(path, " -> "))
+	}
+	cuts, err := gorbac.MinimalCutSets(ctx, rbac, role, p)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitUsage
+	}
+	if cuts == nil {
+		fmt.Fprintf(stdout, "%s holds %s directly; no parent edge removal revokes it\n", role, p.ID())
+		return exitOK
+	}
+	fmt.Fprintln(stdout, "minimal cut sets:")
+	for _, cut := range cuts {
+		edges := make([]string, len(cut))
+		for i, e := range cut {
+			edges[i] = e.Role + " -> " + e.Parent
+		}
+		fmt.Fprintf(stdout, "\t%s\n", strings.Join(edges, ", "))
+	}
+	return exitOK
+}
+
+func runDiff(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flagSet("diff", stderr)
+	if !parse(fs, args, 2) {
+		return exitUsage
+	}
+	from, err := policy.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitUsage
+	}
+	to, err := policy.Load(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitUsage
+	}
+	changes := policy.Diff(from, to)
+	for _, c := range changes {
+		fmt.Fprintln(stdout, c.String())
+	}
+	if len(changes) > 0 {
+		return exitFail
+	}
+	return exitOK
+}
+
+// runFmt prints a policy in the text policy language, or as a JSON document.
+func runFmt(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flagSet("fmt", stderr)
+	asJSON := fs.Bool("json", false, "print a JSON document instead")
+	if !parse(fs, args, 1) {
+		return exitUsage
+	}
+	doc, err := policy.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitUsage
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(doc)
+	} else {
+		err = policy.FormatText(stdout, doc)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitFail
+	}
+	return exitOK
+}
+
+func runRender(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flagSet("render", stderr)
+	schemaFile := fs.String("schema", "", "filter.SchemaSpec JSON file")
+	dialect := fs.String("dialect", string(filter.DialectPostgres), "SQL dialect: sqlite, mysql, postgres or postgres_pgx")
+	bindings := fs.String("bindings", "", "JSON object of CEL variable bindings")
+	if !parse(fs, args, 1) {
+		return exitUsage
+	}
+	if *schemaFile == "" {
+		fmt.Fprintln(stderr, "gorbac: -schema is required")
+		return exitUsage
+	}
+	schema, ok := loadSchema(*schemaFile, stderr)
+	if !ok {
+		return exitUsage
+	}
+	var vars filter.Bindings
+	if *bindings != "" {
+		if err := json.Unmarshal([]byte(*bindings), &vars); err != nil {
+			fmt.Fprintf(stderr, "gorbac: -bindings: %v\n", err)
+			return exitUsage
+		}
+	}
+	engine, err := filter.NewEngine(schema)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitUsage
+	}
+	stmt, err := engine.CompileToStatement(fs.Arg(0), vars, filter.RenderOptions{Dialect: filter.DialectName(*dialect)})
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitFail
+	}
+	fmt.Fprintln(stdout, stmt.SQL)
+	printed := any(stmt.Args)
+	if stmt.NamedArgs != nil {
+		printed = stmt.NamedArgs
+	}
+	if encoded, err := json.Marshal(printed); err == nil && string(encoded) != "null" {
+		fmt.Fprintf(stdout, "-- args: %s\n", encoded)
+	}
+	return exitOK
+}
+
+func runTest(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flagSet("test", stderr)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	code := exitOK
+	for _, name := range fs.Args() {
+		suite, err := policytest.Load(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "gorbac: %v\n", err)
+			return exitUsage
+		}
+		result, err := suite.Run(ctx)
+		if err != nil {
+			fmt.Fprintf(stderr, "gorbac: %s: %v\n", name, err)
+			return exitUsage
+		}
+		for _, f := range result.Failures {
+			fmt.Fprintf(stdout, "FAIL %s: %s\n", name, f)
+		}
+		if result.OK() {
+			fmt.Fprintf(stdout, "ok   %s: %d cases\n", name, result.Cases)
+		} else {
+			fmt.Fprintf(stdout, "FAIL %s: %d of %d cases failed\n", name, len(result.Failures), result.Cases)
+			code = exitFail
+		}
+	}
+	return code
+}
+
+func runGraph(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flagSet("graph", stderr)
+	spec := fs.String("policy", "", "policy file")
+	format := fs.String("format", "dot", "output format: dot or mermaid")
+	perms := fs.String("perms", "none", "permissions listed per role: none, direct or effective")
+	role := fs.String("role", "", "highlight the path granting -permission to `ROLE`")
+	perm := fs.String("permission", "", "permission of the highlighted decision")
+	sep := fs.String("sep", "", "the highlighted permission is layered, split by `SEP`")
+	if !parse(fs, args, 0) {
+		return exitUsage
+	}
+	write := gorbac.WriteDOT[string, string]
+	switch *format {
+	case "dot":
+	case "mermaid":
+		write = gorbac.WriteMermaid[string, string]
+	default:
+		fmt.Fprintf(stderr, "gorbac: unknown format %q\n", *format)
+		return exitUsage
+	}
+	var opts []gorbac.GraphOption
+	switch *perms {
+	case "none":
+	case "direct":
+		opts = append(opts, gorbac.WithGraphPermissions())
+	case "effective":
+		opts = append(opts, gorbac.WithGraphEffectivePermissions())
+	default:
+		fmt.Fprintf(stderr, "gorbac: unknown -perms %q\n", *perms)
+		return exitUsage
+	}
+	if (*role == "") != (*perm == "") {
+		fmt.Fprintln(stderr, "gorbac: -role and -permission go together")
+		return exitUsage
+	}
+	rbac, ok := loadRBAC(ctx, *spec, stderr)
+	if !ok {
+		return exitUsage
+	}
+	if *role != "" {
+		path, ok := gorbac.GrantPath(ctx, rbac, *role, permission(*perm, *sep))
+		if !ok {
+			// highlight the role alone to show where the search started
+			path = []string{*role}
+		}
+		opts = append(opts, gorbac.WithGraphHighlight(path...))
+	}
+	if err := write(ctx, stdout, rbac, opts...); err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitFail
+	}
+	return exitOK
+}
+
+func runLint(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flagSet("lint", stderr)
+	spec := fs.String("policy", "", "policy file")
+	schemaFile := fs.String("schema", "", "compile filter permissions against this filter.SchemaSpec file")
+	if !parse(fs, args, 0) {
+		return exitUsage
+	}
+	// cycles are reported as lint findings
+	rbac, ok := loadPolicy(ctx, *spec, stderr)
+	if !ok {
+		return exitUsage
+	}
+	var opts []rbaclint.Option
+	if *schemaFile != "" {
+		schema, ok := loadSchema(*schemaFile, stderr)
+		if !ok {
+			return exitUsage
+		}
+		opts = append(opts, rbaclint.WithSchema(schema))
+	}
+	findings, err := rbaclint.Analyze(ctx, rbac, opts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitUsage
+	}
+	code := exitOK
+	for _, f := range findings {
+		fmt.Fprintln(stdout, f)
+		if f.Severity >= rbaclint.Warning {
+			code = exitFail
+		}
+	}
+	return code
+}
+
+func runRecommend(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flagSet("recommend", stderr)
+	spec := fs.String("policy", "", "policy file")
+	logFile := fs.String("log", "", "decision log written by gorbac.JSONLinesDecisionSink")
+	since := fs.String("since", "", "only consider decisions from this `DATE` (YYYY-MM-DD or RFC 3339)")
+	until := fs.String("until", "", "only consider decisions before this `DATE`")
+	narrow := fs.String("narrow", "", "propose narrower roles named ROLE+`SUFFIX`")
+	out := fs.String("o", "", "write the policy with the plan applied to `FILE`")
+	if !parse(fs, args, 0) {
+		return exitUsage
+	}
+	if *logFile == "" {
+		fmt.Fprintln(stderr, "gorbac: -log is required")
+		return exitUsage
+	}
+	var opts []rbacrecommend.Option
+	from, err := parseTime(*since)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: -since: %v\n", err)
+		return exitUsage
+	}
+	to, err := parseTime(*until)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: -until: %v\n", err)
+		return exitUsage
+	}
+	opts = append(opts, rbacrecommend.WithPeriod(from, to))
+	if *narrow != "" {
+		opts = append(opts, rbacrecommend.WithNarrowRoleID(func(id string) string { return id + *narrow }))
+	}
+	rbac, ok := loadRBAC(ctx, *spec, stderr)
+	if !ok {
+		return exitUsage
+	}
+	f, err := os.Open(*logFile)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitUsage
+	}
+	decisions, err := gorbac.ReadDecisions[string, string](f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %s: %v\n", *logFile, err)
+		return exitUsage
+	}
+	plan, err := rbacrecommend.Recommend(ctx, rbac, decisions, opts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitUsage
+	}
+	for _, c := range plan.Changes {
+		fmt.Fprintln(stdout, c)
+		if len(c.Subjects) > 0 {
+			fmt.Fprintf(stdout, "\tsubjects: %s\n", strings.Join(c.Subjects, ", "))
+		}
+	}
+	if *out == "" {
+		return exitOK
+	}
+	if err := plan.Apply(ctx, rbac); err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitFail
+	}
+	doc, err := policy.FromRBAC(ctx, rbac)
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitFail
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err == nil {
+		err = os.WriteFile(*out, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gorbac: %v\n", err)
+		return exitFail
+	}
+	return exitOK
+}
+
+// parseTime accepts a date or an RFC 3339 time; empty means unbounded.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const persistence = "../../examples/persistence/roles.json,../../examples/persistence/inher.json"
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	cycle := writeFile(t, "cycle.json", `{"roles": {"a": [], "b": []}, "parents": {"a": ["b", "c"], "b": ["a"]}}`)
+	cyclic := writeFile(t, "cyclic.json", `{"roles": {"a": [], "b": []}, "parents": {"a": ["b"], "b": ["a"]}}`)
+	changed := writeFile(t, "changed.json", `{"roles": {"editor": ["add-text"]}}`)
+	lintable := writeFile(t, "lintable.json", `{"roles": {"base": ["read"],
+		"editor": ["read", {"id": "posts", "filter": "unknown == 1"}]}, "parents": {"editor": ["base"]}}`)
+	decisions := writeFile(t, "decisions.jsonl",
+		`{"time": "2026-10-01T00:00:00Z", "subject": "alice", "roles": ["chief-editor"], "permissions": ["add-text"], "granted": true}`+"\n"+
+			`{"time": "2026-08-01T00:00:00Z", "subject": "bob", "roles": ["photographer"], "permissions": ["add-photo"], "granted": true}`+"\n")
+	recommended := filepath.Join(t.TempDir(), "recommended.json")
+	text := writeFile(t, "policy.rbac", "role viewer { read }\nrole editor inherits viewer {\n\twrite\n}\n")
+	broken := writeFile(t, "broken.rbac", "role editor inherits viewer\n")
+	schema := writeFile(t, "schema.json", `{"name": "project", "table": "project",
+		"fields": {"creator_id": {"type": "int"}}, "variables": {"uid": "int"}}`)
+
+	tests := []struct {
+		args   []string
+		code   int
+		stdout []string
+	}{
+		{[]string{"validate", "-policy", persistence}, exitOK, []string{"ok: 3 roles"}},
+		{[]string{"validate", "-policy", cycle}, exitFail, []string{`a: parent "c" is not declared`, "inheritance found circle"}},
+		{[]string{"check", "-policy", persistence, "chief-editor", "add-text"}, exitOK, []string{"granted"}},
+		{[]string{"check", "-policy", cyclic, "a", "z"}, exitUsage, nil},
+		{[]string{"graph", "-policy", cyclic}, exitUsage, nil},
+		{[]string{"recommend", "-policy", cyclic, "-log", decisions}, exitUsage, nil},
+		{[]string{"lint", "-policy", cyclic}, exitFail, []string{"error: a: role inherits from itself"}},
+		{[]string{"check", "-policy", persistence, "photographer", "add-text"}, exitFail, []string{"denied"}},
+		{[]string{"check", "-policy", persistence, "nobody", "add-text"}, exitUsage, nil},
+		{[]string{"perms", "-policy", persistence, "photographer"}, exitOK, []string{"add-photo\nedit-photo\n"}},
+		{[]string{"explain", "-policy", persistence, "chief-editor", "add-photo"}, exitOK,
+			[]string{"granted: chief-editor -> photographer", "photographer holds add-photo"}},
+		{[]string{"explain", "-policy", persistence, "editor", "del-text"}, exitFail, []string{"denied"}},
+		{[]string{"explain", "-policy", persistence, "-all", "chief-editor", "add-photo"}, exitOK,
+			[]string{"paths:\n\tchief-editor -> photographer\n", "minimal cut sets:\n\tchief-editor -> photographer\n"}},
+		{[]string{"explain", "-policy", persistence, "-all", "chief-editor", "del-text"}, exitOK, []string{"holds del-text directly"}},
+		{[]string{"diff", persistence, persistence}, exitOK, nil},
+		{[]string{"diff", persistence, changed}, exitFail, []string{"- role chief-editor", "- editor permission edit-text"}},
+		{[]string{"fmt", persistence}, exitOK, []string{"role chief-editor inherits editor, photographer {\n\tdel-text\n"}},
+		{[]string{"fmt", "-json", text}, exitOK, []string{`"editor": [`, `"parents": {`}},
+		{[]string{"check", "-policy", text, "editor", "read"}, exitOK, []string{"granted"}},
+		{[]string{"validate", "-policy", broken}, exitUsage, nil},
+		{[]string{"render", "-schema", schema, "-dialect", "sqlite", "-bindings", `{"uid": 7}`, "creator_id == uid"}, exitOK,
+			[]string{"`project`.`creator_id` = ?", "-- args: [7]"}},
+		{[]string{"render", "-schema", schema, "unknown == 1"}, exitFail, nil},
+		{[]string{"test", "../../policytest/testdata/policy_test.json"}, exitOK, []string{"ok   ../../policytest/testdata/policy_test.json: 5 cases"}},
+		{[]string{"test", "../../policytest/testdata/policy_test.json", "../../policytest/testdata/failing_test.json"}, exitFail,
+			[]string{"FAIL ../../policytest/testdata/failing_test.json: 3 of 3 cases failed"}},
+		{[]string{"test"}, exitUsage, nil},
+		{[]string{"recommend", "-policy", persistence, "-log", decisions, "-since", "2026-09-01", "-narrow", "-minimal",
+			"-o", recommended}, exitOK, []string{
+			"add-role chief-editor-minimal: add-text (narrows chief-editor: 1 of 7 permissions exercised since 2026-09-01)\n" +
+				"\tsubjects: alice\n",
+			"revoke photographer: add-photo, edit-photo (never exercised since 2026-09-01)",
+		}},
+		{[]string{"recommend", "-policy", persistence}, exitUsage, nil},
+		{[]string{"recommend", "-policy", persistence, "-log", decisions, "-since", "yesterday"}, exitUsage, nil},
+		{[]string{"lint", "-policy", persistence}, exitOK, nil},
+		{[]string{"lint", "-policy", lintable, "-schema", schema}, exitFail,
+			[]string{"error: editor: filter of posts", "warning: editor: read is already inherited from base (redundant-permission)"}},
+		{[]string{"graph", "-policy", persistence}, exitOK, []string{"digraph rbac {", "n0 -> n1;"}},
+		{[]string{"graph", "-policy", persistence, "-format", "mermaid", "-perms", "effective",
+			"-role", "chief-editor", "-permission", "add-photo"}, exitOK,
+			[]string{"graph BT", "inherited: add-photo", "style n0 ", "linkStyle 1 "}},
+		{[]string{"graph", "-policy", persistence, "-format", "svg"}, exitUsage, nil},
+		{[]string{"graph", "-policy", persistence, "-role", "editor"}, exitUsage, nil},
+		{[]string{"check", "-policy", persistence, "editor"}, exitUsage, nil},
+		{[]string{"unknown"}, exitUsage, nil},
+		{nil, exitUsage, nil},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), tt.args, &stdout, &stderr)
+		if code != tt.code {
+			t.Fatalf("%v: exit code %d expected, got %d (%s%s)", tt.args, tt.code, code, stdout.String(), stderr.String())
+		}
+		for _, want := range tt.stdout {
+			if !strings.Contains(stdout.String(), want) {
+				t.Fatalf("%v: %q expected in %q", tt.args, want, stdout.String())
+			}
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"check", "-policy", recommended, "chief-editor-minimal", "add-text"},
+		&stdout, &stderr); code != exitOK {
+		t.Fatalf("the recommended policy should be written, got %s%s", stdout.String(), stderr.String())
+	}
+}
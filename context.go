@@ -0,0 +1,89 @@
+package gorbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoRolesInContext occurred if the context carries no role IDs
+var ErrNoRolesInContext = errors.New("No roles in context")
+
+type subjectKey struct{}
+
+type rolesKey struct{}
+
+// WithSubject returns a copy of `ctx` carrying the subject (user, service
+// account, ...) being authorized.
+func WithSubject[S any](ctx context.Context, subject S) context.Context {
+	return context.WithValue(ctx, subjectKey{}, subject)
+}
+
+// SubjectFromContext returns the subject attached by WithSubject.
+// It reports false when there is none or it is not of type S.
+func SubjectFromContext[S any](ctx context.Context) (S, bool) {
+	subject, ok := ctx.Value(subjectKey{}).(S)
+	return subject, ok
+}
+
+// subjectString formats the subject attached by WithSubject for logging.
+func subjectString(ctx context.Context) string {
+	switch subject := ctx.Value(subjectKey{}).(type) {
+	case nil:
+		return ""
+	case string:
+		return subject
+	default:
+		return fmt.Sprint(subject)
+	}
+}
+
+// WithRoles returns a copy of `ctx` carrying the role IDs of the subject.
+func WithRoles[R comparable](ctx context.Context, roles ...R) context.Context {
+	return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+// RolesFromContext returns the role IDs attached by WithRoles.
+// It reports false when there are none or they are not of type R.
+func RolesFromContext[R comparable](ctx context.Context) ([]R, bool) {
+	roles, ok := ctx.Value(rolesKey{}).([]R)
+	return roles, ok
+}
+
+// GrantedFromContext checks whether the roles attached to `ctx` grant any
+// specified permission. It returns false when `ctx` carries no roles.
+func GrantedFromContext[R, P comparable](ctx context.Context, rbac RBACOf[R, P],
+	permissions ...Permission[P]) bool {
+	roles, _ := RolesFromContext[R](ctx)
+	return AnyGranted(ctx, rbac, roles, permissions...)
+}
+
+// AllGrantedFromContext checks whether the roles attached to `ctx` grant all
+// specified permissions. It returns false when `ctx` carries no roles.
+func AllGrantedFromContext[R, P comparable](ctx context.Context, rbac RBACOf[R, P],
+	permissions ...Permission[P]) bool {
+	roles, _ := RolesFromContext[R](ctx)
+	return AllGranted(ctx, rbac, roles, permissions...)
+}
+
+// CheckFromContext is the error-aware variant of GrantedFromContext.
+// ErrNoRolesInContext is returned when `ctx` carries no roles.
+func CheckFromContext[R, P comparable](ctx context.Context, rbac RBACOf[R, P],
+	permissions ...Permission[P]) (bool, error) {
+	roles, ok := RolesFromContext[R](ctx)
+	if !ok {
+		return false, ErrNoRolesInContext
+	}
+	return CheckAny(ctx, rbac, roles, permissions...)
+}
+
+// FilterExprsFromContext is FilterExprsForRoles for the roles attached to `ctx`.
+// ErrNoRolesInContext is returned when `ctx` carries no roles.
+func FilterExprsFromContext[R, P comparable](ctx context.Context, rbac RBACOf[R, P],
+	requiredFilterPermissions []Permission[P]) ([]string, error) {
+	roles, ok := RolesFromContext[R](ctx)
+	if !ok {
+		return nil, ErrNoRolesInContext
+	}
+	return FilterExprsForRoles(ctx, rbac, roles, requiredFilterPermissions)
+}
@@ -0,0 +1,59 @@
+package gorbac
+
+import (
+	"context"
+	"testing"
+)
+
+type testUser struct {
+	Name string
+}
+
+func (u testUser) String() string {
+	return "user:" + u.Name
+}
+
+func TestContextHelpers(t *testing.T) {
+	ctx := context.Background()
+	rbac := New[string]()
+	rA, rB := NewRole("role-a"), NewRole("role-b")
+	assert(t, rA.Assign(ctx, pA))
+	assert(t, rB.Assign(ctx, NewFilterPermission("permission-b", "creator_id == 1")))
+	assert(t, rbac.Add(ctx, rA))
+	assert(t, rbac.Add(ctx, rB))
+
+	if GrantedFromContext(ctx, rbac, pA) {
+		t.Fatal("a context without roles should not be granted")
+	}
+	if _, err := CheckFromContext(ctx, rbac, pA); err != ErrNoRolesInContext {
+		t.Fatalf("%s needed", ErrNoRolesInContext)
+	}
+
+	ctx = WithSubject(WithRoles(ctx, "role-a", "role-b"), testUser{"alice"})
+	if u, ok := SubjectFromContext[testUser](ctx); !ok || u.Name != "alice" {
+		t.Fatal("subject should be alice")
+	}
+	if _, ok := SubjectFromContext[string](ctx); ok {
+		t.Fatal("subject is not a string")
+	}
+	if !GrantedFromContext(ctx, rbac, pA) || AllGrantedFromContext(ctx, rbac, pA, pC) {
+		t.Fatalf("roles from context should have %s but not %s", pA, pC)
+	}
+	if ok, err := CheckFromContext(ctx, rbac, pB); err != nil || !ok {
+		t.Fatalf("roles from context should have %s: %v", pB, err)
+	}
+	exprs, err := FilterExprsFromContext(ctx, rbac, []Permission[string]{pB})
+	assert(t, err)
+	if len(exprs) != 2 || exprs[0] != "true" || exprs[1] != "(creator_id == 1)" {
+		t.Fatalf("unexpected filter expressions %v", exprs)
+	}
+
+	var subject string
+	rbac.SetDecisionLogger(DecisionLoggerFunc[string, string](func(_ context.Context, d Decision[string, string]) {
+		subject = d.Subject
+	}))
+	rbac.IsGranted(ctx, "role-a", pA)
+	if subject != "user:alice" {
+		t.Fatalf("user:alice expected, but %q got", subject)
+	}
+}
@@ -0,0 +1,230 @@
+package gorbac
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Edge is an inheritance edge: Role inherits from Parent.
+type Edge[R comparable] struct {
+	Role   R
+	Parent R
+}
+
+// GrantPaths enumerates every inheritance path through which the role `id`
+// is granted `p`. Each path starts with `id` and ends with a role permitting
+// `p`; no role appears twice in a path and a path stops at the first role
+// permitting `p`. Paths are sorted by length. Like GrantPath, implications
+// bound to a StdRBAC are not considered.
+//
+// The number of paths can grow exponentially with the hierarchy depth.
+func GrantPaths[R, P comparable](ctx context.Context, rbac RBACOf[R, P], id R, p Permission[P]) ([][]R, error) {
+	if p == nil {
+		return nil, nil
+	}
+	g := &grantGraph[R, P]{rbac: rbac, p: p, permits: make(map[R]bool), parents: make(map[R][]R)}
+	var paths [][]R
+	onPath := make(map[R]bool)
+	var visit func(path []R) error
+	visit = func(path []R) error {
+		rid := path[len(path)-1]
+		permits, parents, err := g.node(ctx, rid)
+		if err != nil {
+			return err
+		}
+		if permits {
+			paths = append(paths, slices.Clone(path))
+			return nil
+		}
+		onPath[rid] = true
+		defer delete(onPath, rid)
+		for _, parent := range parents {
+			if onPath[parent] {
+				continue
+			}
+			if err := visit(append(path, parent)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, err := rbac.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := visit([]R{id}); err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(paths, func(a, b []R) int {
+		return cmp.Or(cmp.Compare(len(a), len(b)), cmp.Compare(fmt.Sprint(a), fmt.Sprint(b)))
+	})
+	return paths, nil
+}
+
+// PermissionPaths returns the grant paths of every role granted `p`, keyed
+// by role, answering "which roles can reach `p`, and how".
+func PermissionPaths[R, P comparable](ctx context.Context, rbac RBACOf[R, P], p Permission[P]) (map[R][][]R, error) {
+	result := make(map[R][][]R)
+	for _, id := range rbac.RoleIDs(ctx) {
+		paths, err := GrantPaths(ctx, rbac, id, p)
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) > 0 {
+			result[id] = paths
+		}
+	}
+	return result, nil
+}
+
+// MinimalCutSets returns the minimal sets of inheritance edges whose removal
+// stops the role `id` from being granted `p`. Removing every edge of any
+// returned set cuts all grant paths, and no edge of a set can be spared.
+//
+// The result is nil when `id` permits `p` itself, as no edge removal helps,
+// and holds a single empty set when `id` is not granted `p` at all. Sets are
+// sorted by size.
+func MinimalCutSets[R, P comparable](ctx context.Context, rbac RBACOf[R, P], id R, p Permission[P]) ([][]Edge[R], error) {
+	paths, err := GrantPaths(ctx, rbac, id, p)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[Edge[R]]int)
+	var edges []Edge[R]
+	pathEdges := make([][]int, 0, len(paths))
+	for _, path := range paths {
+		if len(path) == 1 {
+			return nil, nil
+		}
+		var set []int
+		for i := 1; i < len(path); i++ {
+			e := Edge[R]{Role: path[i-1], Parent: path[i]}
+			n, ok := index[e]
+			if !ok {
+				n = len(edges)
+				index[e] = n
+				edges = append(edges, e)
+			}
+			set = append(set, n)
+		}
+		slices.Sort(set)
+		pathEdges = append(pathEdges, slices.Compact(set))
+	}
+
+	// Berge's algorithm: extend the minimal transversals path by path.
+	transversals := [][]int{{}}
+	for _, set := range pathEdges {
+		var next [][]int
+		for _, t := range transversals {
+			if intersects(t, set) {
+				next = append(next, t)
+				continue
+			}
+			for _, e := range set {
+				extended := append(slices.Clone(t), e)
+				slices.Sort(extended)
+				next = append(next, extended)
+			}
+		}
+		transversals = minimalSets(next)
+	}
+
+	result := make([][]Edge[R], 0, len(transversals))
+	for _, t := range transversals {
+		cut := make([]Edge[R], 0, len(t))
+		for _, n := range t {
+			cut = append(cut, edges[n])
+		}
+		slices.SortFunc(cut, compareEdges)
+		result = append(result, cut)
+	}
+	slices.SortStableFunc(result, func(a, b []Edge[R]) int {
+		return cmp.Or(cmp.Compare(len(a), len(b)), cmp.Compare(fmt.Sprint(a), fmt.Sprint(b)))
+	})
+	return result, nil
+}
+
+// grantGraph memoizes role lookups while enumerating paths.
+type grantGraph[R, P comparable] struct {
+	rbac    RBACOf[R, P]
+	p       Permission[P]
+	permits map[R]bool
+	parents map[R][]R
+}
+
+func (g *grantGraph[R, P]) node(ctx context.Context, id R) (bool, []R, error) {
+	if permits, ok := g.permits[id]; ok {
+		return permits, g.parents[id], nil
+	}
+	role, err := g.rbac.Get(ctx, id)
+	if err != nil {
+		// a dangling parent grants nothing
+		g.permits[id] = false
+		return false, nil, nil
+	}
+	parents, err := g.rbac.GetParents(ctx, id)
+	if err != nil {
+		return false, nil, err
+	}
+	parents = slices.Clone(parents)
+	slices.SortFunc(parents, func(a, b R) int { return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b)) })
+	permits := role.Permit(ctx, g.p)
+	g.permits[id], g.parents[id] = permits, parents
+	return permits, parents, nil
+}
+
+func compareEdges[R comparable](a, b Edge[R]) int {
+	return cmp.Or(cmp.Compare(fmt.Sprint(a.Role), fmt.Sprint(b.Role)), cmp.Compare(fmt.Sprint(a.Parent), fmt.Sprint(b.Parent)))
+}
+
+// intersects reports whether the sorted sets share an element.
+func intersects(a, b []int) bool {
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			return true
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return false
+}
+
+// minimalSets drops duplicates and supersets of other sets.
+func minimalSets(sets [][]int) [][]int {
+	slices.SortFunc(sets, func(a, b []int) int {
+		return cmp.Or(cmp.Compare(len(a), len(b)), slices.Compare(a, b))
+	})
+	var result [][]int
+	for _, s := range sets {
+		minimal := true
+		for _, r := range result {
+			if isSubset(r, s) {
+				minimal = false
+				break
+			}
+		}
+		if minimal {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// isSubset reports whether the sorted set a is a subset of the sorted set b.
+func isSubset(a, b []int) bool {
+	j := 0
+	for _, v := range a {
+		for j < len(b) && b[j] < v {
+			j++
+		}
+		if j == len(b) || b[j] != v {
+			return false
+		}
+		j++
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package gorbac
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func escalationFixture(t *testing.T) *StdRBAC[string] {
+	ctx := context.Background()
+	rbac := New[string]()
+	for _, id := range []string{"user", "a", "b", "c", "admin", "loop"} {
+		role := NewRole(id)
+		if id == "admin" || id == "c" {
+			assert(t, role.Assign(ctx, NewPermission("delete-user")))
+		}
+		assert(t, rbac.Add(ctx, role))
+	}
+	assert(t, rbac.SetParents(ctx, "user", "a", "b", "c", "loop"))
+	assert(t, rbac.SetParents(ctx, "a", "admin"))
+	assert(t, rbac.SetParents(ctx, "b", "admin"))
+	assert(t, rbac.SetParents(ctx, "loop", "user"))
+	return rbac
+}
+
+func TestGrantPaths(t *testing.T) {
+	ctx := context.Background()
+	rbac := escalationFixture(t)
+	p := NewPermission("delete-user")
+
+	paths, err := GrantPaths(ctx, rbac, "user", p)
+	assert(t, err)
+	if got := fmt.Sprint(paths); got != "[[user c] [user a admin] [user b admin]]" {
+		t.Fatalf("unexpected paths %s", got)
+	}
+	if _, err := GrantPaths(ctx, rbac, "not-exist", p); err != ErrRoleNotExist {
+		t.Fatalf("%s needed", ErrRoleNotExist)
+	}
+
+	all, err := PermissionPaths(ctx, rbac, p)
+	assert(t, err)
+	if len(all) != 6 || fmt.Sprint(all["admin"]) != "[[admin]]" || len(all["loop"]) != 3 {
+		t.Fatalf("unexpected paths %v", all)
+	}
+}
+
+func TestMinimalCutSets(t *testing.T) {
+	ctx := context.Background()
+	rbac := escalationFixture(t)
+	p := NewPermission("delete-user")
+
+	cuts, err := MinimalCutSets(ctx, rbac, "user", p)
+	assert(t, err)
+	want := "[" +
+		"[{a admin} {b admin} {user c}] " +
+		"[{a admin} {user b} {user c}] " +
+		"[{b admin} {user a} {user c}] " +
+		"[{user a} {user b} {user c}]]"
+	if got := fmt.Sprint(cuts); got != want {
+		t.Fatalf("unexpected cut sets %s", got)
+	}
+	// removing a cut set denies the permission, sparing any edge of it does not
+	granted := func(removed []Edge[string]) bool {
+		rbac := escalationFixture(t)
+		for _, e := range removed {
+			assert(t, rbac.RemoveParents(ctx, e.Role, e.Parent))
+		}
+		paths, err := GrantPaths(ctx, rbac, "user", p)
+		assert(t, err)
+		return len(paths) > 0
+	}
+	for _, cut := range cuts {
+		if granted(cut) {
+			t.Fatalf("cut set %v does not deny the permission", cut)
+		}
+		for i := range cut {
+			if !granted(append(append([]Edge[string](nil), cut[:i]...), cut[i+1:]...)) {
+				t.Fatalf("cut set %v is not minimal", cut)
+			}
+		}
+	}
+
+	if cuts, err := MinimalCutSets(ctx, rbac, "admin", p); err != nil || cuts != nil {
+		t.Fatalf("a direct grant cannot be cut, got %v, %v", cuts, err)
+	}
+	if cuts, err := MinimalCutSets(ctx, rbac, "loop", NewPermission("none")); err != nil || len(cuts) != 1 || len(cuts[0]) != 0 {
+		t.Fatalf("a single empty cut set expected, got %v, %v", cuts, err)
+	}
+}
@@ -13,6 +13,7 @@ type roleRepo[T comparable] interface {
 	RemoveRole(id T) error
 	AddParent(id, parent T) error
 	RemoveParents(id T, parents ...T) error
+	Ping(ctx context.Context) error
 }
 
 type memoryRepo[T comparable] struct {
@@ -27,6 +28,10 @@ func newMemoryRepo[T comparable]() *memoryRepo[T] {
 	}
 }
 
+func (r *memoryRepo[T]) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
 func (r *memoryRepo[T]) AddRole(id T) error {
 	r.roles[id] = struct{}{}
 	return nil
@@ -119,6 +124,15 @@ func (r *MyRBAC[T]) IsGranted(ctx context.Context, id T, p gorbac.Permission[T])
 	return r.inner.IsGranted(ctx, id, p)
 }
 
+// Check lets callers see storage errors instead of a silent deny.
+// gorbac.Check, gorbac.CheckAny and gorbac.CheckAll prefer it over IsGranted.
+func (r *MyRBAC[T]) Check(ctx context.Context, id T, p gorbac.Permission[T]) (bool, error) {
+	if err := r.repo.Ping(ctx); err != nil {
+		return false, err
+	}
+	return r.inner.Check(ctx, id, p)
+}
+
 func main() {
 	ctx := context.Background()
 	repo := newMemoryRepo[string]()
@@ -148,6 +162,11 @@ func main() {
 	if rbac.IsGranted(ctx, "admin", read) {
 		fmt.Println("admin can read")
 	}
+	if ok, err := gorbac.CheckAll(ctx, rbac, []string{"user"}, read); err != nil {
+		log.Fatal(err)
+	} else if ok {
+		fmt.Println("user can read")
+	}
 
 	fmt.Printf("repo roles: %d\n", len(repo.roles))
 	fmt.Printf("repo parents: %d\n", len(repo.parents["admin"]))
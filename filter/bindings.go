@@ -1,6 +1,10 @@
 package filter
 
-import "reflect"
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
 
 // Bindings provides runtime values for CEL variables which are not schema fields.
 //
@@ -8,6 +12,42 @@ import "reflect"
 // compiled conditions in-memory.
 type Bindings map[string]any
 
+// UnmarshalJSON decodes a JSON object, turning integral numbers into int64
+// and other numbers into float64 so that they match CEL int and double values.
+func (b *Bindings) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	for k, v := range raw {
+		raw[k] = fromJSONNumber(v)
+	}
+	*b = raw
+	return nil
+}
+
+func fromJSONNumber(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i := range v {
+			v[i] = fromJSONNumber(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = fromJSONNumber(v[k])
+		}
+	}
+	return value
+}
+
 func toAnySlice(value any) ([]any, bool) {
 	if value == nil {
 		return nil, false
@@ -0,0 +1,24 @@
+package filter_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/fy0/gorbac/v3/filter"
+)
+
+func TestBindingsUnmarshalJSON(t *testing.T) {
+	var b filter.Bindings
+	if err := json.Unmarshal([]byte(`{"id": 1, "ratio": 0.5, "ids": [1, 2], "name": "x"}`), &b); err != nil {
+		t.Fatal(err)
+	}
+	if b["id"] != int64(1) || b["ratio"] != 0.5 || b["name"] != "x" {
+		t.Fatalf("unexpected bindings %#v", b)
+	}
+	if ids := b["ids"].([]any); ids[0] != int64(1) {
+		t.Fatalf("unexpected ids %#v", ids)
+	}
+	if err := json.Unmarshal([]byte(`[1]`), &b); err == nil {
+		t.Fatal("only objects should be accepted")
+	}
+}
@@ -1,8 +1,10 @@
 package filter
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/cel-go/cel"
 )
@@ -20,6 +22,8 @@ type engineConfig struct {
 	compileHook   []CompileHook
 	sqlPredicates map[string]SQLPredicate
 	extraFilter   string
+
+	instrumentation Instrumentation
 }
 
 // EngineOption customizes Engine construction.
@@ -79,6 +83,8 @@ type Engine struct {
 	compileHooks  []CompileHook
 	sqlPredicates map[string]SQLPredicate
 	extraFilter   string
+
+	instrumentation Instrumentation
 }
 
 // NewEngine builds a new Engine for the provided schema.
@@ -108,6 +114,8 @@ func NewEngine(schema Schema, opts ...EngineOption) (*Engine, error) {
 		compileHooks:  cfg.compileHook,
 		sqlPredicates: cfg.sqlPredicates,
 		extraFilter:   cfg.extraFilter,
+
+		instrumentation: cfg.instrumentation,
 	}, nil
 }
 
@@ -118,10 +126,17 @@ func (e *Engine) ExtraFilterCEL() string {
 	return e.extraFilter
 }
 
+// Schema returns the schema the Engine compiles against.
+func (e *Engine) Schema() Schema {
+	return e.schema
+}
+
 // Program stores a compiled filter condition.
 type Program struct {
 	schema    Schema
 	condition Condition
+
+	instrumentation Instrumentation
 }
 
 // ConditionTree exposes the underlying condition tree.
@@ -154,6 +169,25 @@ func (p *Program) IsCondGranted(vars map[string]any, opts ...EvalOptions) (bool,
 
 // Compile parses the filter string into an executable program.
 func (e *Engine) Compile(filter string) (*Program, error) {
+	return e.CompileContext(context.Background(), filter)
+}
+
+// CompileContext is Compile reporting to the instrumentation with `ctx`.
+func (e *Engine) CompileContext(ctx context.Context, filter string) (*Program, error) {
+	if e.instrumentation == nil {
+		return e.compile(filter)
+	}
+	start := time.Now()
+	program, err := e.compile(filter)
+	e.instrumentation.ObserveCompile(ctx, CompileEvent{
+		Schema:   e.schema.Name,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+	return program, err
+}
+
+func (e *Engine) compile(filter string) (*Program, error) {
 	if strings.TrimSpace(filter) == "" {
 		return nil, fmt.Errorf("filter expression is empty")
 	}
@@ -185,6 +219,8 @@ func (e *Engine) Compile(filter string) (*Program, error) {
 	return &Program{
 		schema:    e.schema,
 		condition: cond,
+
+		instrumentation: e.instrumentation,
 	}, nil
 }
 
@@ -248,6 +284,23 @@ type Statement struct {
 // should build on Schema, ConditionTree, and the Walk* helpers instead of SQL
 // renderer internals.
 func (p *Program) RenderSQL(bindings Bindings, opts RenderOptions) (Statement, error) {
+	return p.RenderSQLContext(context.Background(), bindings, opts)
+}
+
+// RenderSQLContext is RenderSQL reporting to the instrumentation with `ctx`.
+func (p *Program) RenderSQLContext(ctx context.Context, bindings Bindings, opts RenderOptions) (Statement, error) {
+	if p.instrumentation == nil {
+		renderer := newRenderer(p.schema, opts, bindings)
+		return renderer.Render(p.condition)
+	}
+	start := time.Now()
 	renderer := newRenderer(p.schema, opts, bindings)
-	return renderer.Render(p.condition)
+	stmt, err := renderer.Render(p.condition)
+	p.instrumentation.ObserveRender(ctx, RenderEvent{
+		Schema:   p.schema.Name,
+		Dialect:  opts.Dialect,
+		Duration: time.Since(start),
+		Err:      err,
+	})
+	return stmt, err
 }
@@ -0,0 +1,57 @@
+package filter
+
+import (
+	"context"
+	"time"
+)
+
+// CompileEvent describes one Engine.Compile call.
+type CompileEvent struct {
+	Schema   string
+	Duration time.Duration
+	Err      error
+}
+
+// RenderEvent describes one Program.RenderSQL call.
+type RenderEvent struct {
+	Schema   string
+	Dialect  DialectName
+	Duration time.Duration
+	Err      error
+}
+
+// Instrumentation receives measurements from filter compilation and
+// rendering, so that metrics and tracing backends can be plugged in without
+// this package depending on them.
+//
+// Implementations are called synchronously and must be safe for concurrent use.
+// The context is the one given to Engine.CompileContext or
+// Program.RenderSQLContext, so that tracing spans can be parented on the
+// request span.
+type Instrumentation interface {
+	ObserveCompile(ctx context.Context, ev CompileEvent)
+	ObserveRender(ctx context.Context, ev RenderEvent)
+}
+
+// WithInstrumentation reports compilation of the Engine, and rendering of the
+// Programs it compiles, to `inst`.
+func WithInstrumentation(inst Instrumentation) EngineOption {
+	return func(cfg *engineConfig) {
+		cfg.instrumentation = inst
+	}
+}
+
+// Instrumentation returns the Engine's configured instrumentation, or nil.
+func (e *Engine) Instrumentation() Instrumentation {
+	return e.instrumentation
+}
+
+// WithInstrumentation returns a copy of the program reporting RenderSQL calls
+// to `inst`.
+//
+// This is useful for programs built by NewProgramFromCondition.
+func (p *Program) WithInstrumentation(inst Instrumentation) *Program {
+	next := *p
+	next.instrumentation = inst
+	return &next
+}
@@ -0,0 +1,78 @@
+package filter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fy0/gorbac/v3/filter"
+)
+
+type recordingInstrumentation struct {
+	compiles []filter.CompileEvent
+	renders  []filter.RenderEvent
+	ctxs     []context.Context
+}
+
+type spanKey struct{}
+
+func (r *recordingInstrumentation) ObserveCompile(ctx context.Context, ev filter.CompileEvent) {
+	r.ctxs = append(r.ctxs, ctx)
+	r.compiles = append(r.compiles, ev)
+}
+
+func (r *recordingInstrumentation) ObserveRender(ctx context.Context, ev filter.RenderEvent) {
+	r.ctxs = append(r.ctxs, ctx)
+	r.renders = append(r.renders, ev)
+}
+
+func TestEngineInstrumentation(t *testing.T) {
+	inst := &recordingInstrumentation{}
+	engine, err := filter.NewEngine(testSchema(), filter.WithInstrumentation(inst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := engine.Compile(`creator_id == 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Compile(`unknown == 1`); err == nil {
+		t.Fatal("expected compile error")
+	}
+	if _, err := program.RenderSQL(nil, filter.RenderOptions{Dialect: filter.DialectMySQL}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(inst.compiles) != 2 || inst.compiles[0].Err != nil || inst.compiles[1].Err == nil {
+		t.Fatalf("unexpected compile events: %+v", inst.compiles)
+	}
+	if inst.compiles[0].Schema != "test" {
+		t.Fatalf("schema test expected, got %q", inst.compiles[0].Schema)
+	}
+	if len(inst.renders) != 1 || inst.renders[0].Dialect != filter.DialectMySQL || inst.renders[0].Err != nil {
+		t.Fatalf("unexpected render events: %+v", inst.renders)
+	}
+
+	built := filter.NewProgramFromCondition(testSchema(), program.ConditionTree())
+	if _, err := built.RenderSQL(nil, filter.RenderOptions{Dialect: filter.DialectSQLite}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := built.WithInstrumentation(inst).RenderSQL(nil, filter.RenderOptions{Dialect: filter.DialectSQLite}); err != nil {
+		t.Fatal(err)
+	}
+	if len(inst.renders) != 2 {
+		t.Fatalf("2 render events expected, got %d", len(inst.renders))
+	}
+
+	inst.ctxs = nil
+	ctx := context.WithValue(context.Background(), spanKey{}, "span")
+	program, err = engine.CompileContext(ctx, `creator_id == 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := program.RenderSQLContext(ctx, nil, filter.RenderOptions{Dialect: filter.DialectMySQL}); err != nil {
+		t.Fatal(err)
+	}
+	if len(inst.ctxs) != 2 || inst.ctxs[0].Value(spanKey{}) != "span" || inst.ctxs[1].Value(spanKey{}) != "span" {
+		t.Fatal("the context should be passed to the instrumentation")
+	}
+}
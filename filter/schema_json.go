@@ -0,0 +1,132 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+// SchemaSpec is a declarative, JSON-serialisable description of a Schema.
+//
+// It is intended for tooling (e.g. the gorbac CLI) which loads schemas from
+// files instead of Go code:
+//
+//	{
+//	  "name": "project",
+//	  "table": "project",
+//	  "fields": {
+//	    "creator_id": {"type": "int"},
+//	    "title": {"type": "string", "contains": true},
+//	    "tags": {"type": "string", "kind": "json_list", "column": "payload", "json_path": ["tags"]}
+//	  },
+//	  "variables": {"current_user_id": "int"}
+//	}
+type SchemaSpec struct {
+	Name   string               `json:"name"`
+	Table  string               `json:"table"`
+	Fields map[string]FieldSpec `json:"fields"`
+	// Variables declares extra CEL variables provided via Bindings.
+	Variables map[string]FieldType `json:"variables,omitempty"`
+}
+
+// FieldSpec describes one schema field of a SchemaSpec.
+//
+// Omitted values follow SchemaFromStruct: the kind defaults to scalar, the
+// table to SchemaSpec.Table, the column to the field name and the allowed
+// comparison operators to the defaults of the kind and type.
+type FieldSpec struct {
+	Type        FieldType              `json:"type"`
+	Kind        FieldKind              `json:"kind,omitempty"`
+	Table       string                 `json:"table,omitempty"`
+	Column      string                 `json:"column,omitempty"`
+	JSONPath    []string               `json:"json_path,omitempty"`
+	AliasFor    string                 `json:"alias_for,omitempty"`
+	Contains    bool                   `json:"contains,omitempty"`
+	Expressions map[DialectName]string `json:"expressions,omitempty"`
+	// Ops lists the allowed comparison operators, pipe separated
+	// (eq|neq|lt|lte|gt|gte), as in the `filter` struct tag.
+	Ops string `json:"ops,omitempty"`
+}
+
+// SchemaFromJSON decodes a SchemaSpec and builds its Schema.
+func SchemaFromJSON(data []byte) (Schema, error) {
+	var spec SchemaSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return Schema{}, err
+	}
+	return spec.Schema()
+}
+
+// Schema builds the Schema described by the spec.
+func (s SchemaSpec) Schema() (Schema, error) {
+	if strings.TrimSpace(s.Name) == "" {
+		return Schema{}, fmt.Errorf("schema name is required")
+	}
+	fields := make(map[string]*Field, len(s.Fields))
+	envOptions := make([]cel.EnvOption, 0, len(s.Fields)+len(s.Variables))
+	for name, fs := range s.Fields {
+		kind := fs.Kind
+		if kind == "" {
+			kind = FieldKindScalar
+		}
+		def := &Field{
+			Name:             name,
+			Kind:             kind,
+			Type:             fs.Type,
+			JSONPath:         fs.JSONPath,
+			SupportsContains: fs.Contains,
+			Expressions:      fs.Expressions,
+		}
+		if kind == FieldKindVirtualAlias {
+			if strings.TrimSpace(fs.AliasFor) == "" {
+				return Schema{}, fmt.Errorf("field %s: virtual_alias requires alias_for", name)
+			}
+			def.AliasFor = fs.AliasFor
+		} else {
+			def.Column = Column{Table: fs.Table, Name: fs.Column}
+			if def.Column.Table == "" {
+				def.Column.Table = s.Table
+			}
+			if def.Column.Name == "" {
+				def.Column.Name = name
+			}
+			if def.Column.Table == "" {
+				return Schema{}, fmt.Errorf("field %s: table is required", name)
+			}
+		}
+		if (kind == FieldKindJSONBool || kind == FieldKindJSONList) && len(fs.JSONPath) == 0 {
+			return Schema{}, fmt.Errorf("field %s: %s requires json_path", name, kind)
+		}
+		if ops := parseComparisonOps(fs.Ops); ops != nil {
+			def.AllowedComparisonOps = ops
+		} else {
+			def.AllowedComparisonOps = defaultAllowedComparisonOps(kind, fs.Type)
+		}
+		if def.Expressions == nil {
+			def.Expressions = map[DialectName]string{}
+		}
+		celType, err := celTypeForField(def)
+		if err != nil {
+			return Schema{}, fmt.Errorf("field %s: %w", name, err)
+		}
+		fields[name] = def
+		envOptions = append(envOptions, cel.Variable(name, celType))
+	}
+	for name, ft := range s.Variables {
+		if _, exists := fields[name]; exists {
+			return Schema{}, fmt.Errorf("variable %s shadows a field", name)
+		}
+		celType, err := celScalarType(ft)
+		if err != nil {
+			return Schema{}, fmt.Errorf("variable %s: %w", name, err)
+		}
+		envOptions = append(envOptions, cel.Variable(name, celType))
+	}
+	return Schema{
+		Name:       s.Name,
+		Fields:     fields,
+		EnvOptions: envOptions,
+	}, nil
+}
@@ -0,0 +1,54 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/fy0/gorbac/v3/filter"
+)
+
+func TestSchemaFromJSON(t *testing.T) {
+	schema, err := filter.SchemaFromJSON([]byte(`{
+		"name": "project",
+		"table": "project",
+		"fields": {
+			"creator_id": {"type": "int", "ops": "eq|neq"},
+			"title": {"type": "string", "contains": true, "table": "p", "column": "name"},
+			"tags": {"type": "string", "kind": "json_list", "column": "payload", "json_path": ["tags"]}
+		},
+		"variables": {"current_user_id": "int"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := schema.Field("title"); !ok || f.Column.Table != "p" || f.Column.Name != "name" || !f.SupportsContains {
+		t.Fatalf("unexpected title field %+v", f)
+	}
+	if f, _ := schema.Field("creator_id"); f.AllowedComparisonOps[filter.CompareLt] {
+		t.Fatal("creator_id should only allow eq and neq")
+	}
+
+	engine, err := filter.NewEngine(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := engine.CompileToStatement(`creator_id == current_user_id && title.contains("x") && "a" in tags`,
+		filter.Bindings{"current_user_id": 1}, filter.RenderOptions{Dialect: filter.DialectSQLite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmt.Args) != 3 {
+		t.Fatalf("3 args expected, got %v (%s)", stmt.Args, stmt.SQL)
+	}
+
+	for _, bad := range []string{
+		`{"fields": {}}`,
+		`{"name": "x", "fields": {"a": {"type": "int"}}}`,
+		`{"name": "x", "table": "t", "fields": {"a": {"type": "float"}}}`,
+		`{"name": "x", "table": "t", "fields": {"a": {"type": "bool", "kind": "json_bool"}}}`,
+		`{"name": "x", "table": "t", "fields": {"a": {"type": "int"}}, "variables": {"a": "int"}}`,
+	} {
+		if _, err := filter.SchemaFromJSON([]byte(bad)); err == nil {
+			t.Fatalf("%s should be rejected", bad)
+		}
+	}
+}
@@ -8,11 +8,11 @@ import (
 	"github.com/fy0/gorbac/v3/filter"
 )
 
-func collectRoleClosure[T comparable](ctx context.Context, rbac RBAC[T], roleID T) ([]Role[T], bool) {
-	seen := make(map[T]struct{}, 8)
-	closure := make([]Role[T], 0, 8)
-	var dfs func(T)
-	dfs = func(id T) {
+func collectRoleClosure[R, P comparable](ctx context.Context, rbac RBACOf[R, P], roleID R) ([]RoleOf[R, P], bool) {
+	seen := make(map[R]struct{}, 8)
+	closure := make([]RoleOf[R, P], 0, 8)
+	var dfs func(R)
+	dfs = func(id R) {
 		if _, ok := seen[id]; ok {
 			return
 		}
@@ -42,11 +42,11 @@ func collectRoleClosure[T comparable](ctx context.Context, rbac RBAC[T], roleID
 // The required filter permissions are used only to select which filter
 // expressions to compose; missing filters are treated as allow-all. Permission
 // checks are expected to be handled separately.
-func FilterExprsForRoles[T comparable](
+func FilterExprsForRoles[R, P comparable](
 	ctx context.Context,
-	rbac RBAC[T],
-	roles []T,
-	requiredFilterPermissions []Permission[T],
+	rbac RBACOf[R, P],
+	roles []R,
+	requiredFilterPermissions []Permission[P],
 ) ([]string, error) {
 	if len(requiredFilterPermissions) == 0 {
 		return nil, fmt.Errorf("required filter permissions is empty")
@@ -66,17 +66,17 @@ func FilterExprsForRoles[T comparable](
 	return exprs, nil
 }
 
-func filterExprForRole[T comparable](
+func filterExprForRole[R, P comparable](
 	ctx context.Context,
-	rbac RBAC[T],
-	roleID T,
-	requiredFilterPermissions []Permission[T],
+	rbac RBACOf[R, P],
+	roleID R,
+	requiredFilterPermissions []Permission[P],
 ) (string, bool, error) {
 	closure, ok := collectRoleClosure(ctx, rbac, roleID)
 	if !ok {
 		return "", false, nil
 	}
-	exprsByPermission := make(map[T][]string)
+	exprsByPermission := make(map[P][]string)
 	for _, role := range closure {
 		for _, perm := range role.FilterPermissions(ctx) {
 			f, ok := perm.(interface {
@@ -107,7 +107,8 @@ func filterExprForRole[T comparable](
 //
 // Expressions are OR-ed together. Optional `filter.EngineOption` values are
 // forwarded to `filter.NewEngine`, including `filter.WithExtraFilterCEL(...)`
-// which is AND-ed to the final condition.
+// which is AND-ed to the final condition, and `filter.WithInstrumentation(...)`
+// which also observes rendering of the returned program.
 func NewFilterProgramFromCEL(
 	schema filter.Schema,
 	exprs []string,
@@ -121,6 +122,16 @@ func NewFilterProgramFromCEL(
 	if err != nil {
 		return nil, err
 	}
+	return FilterProgramFromEngine(context.Background(), engine, exprs)
+}
+
+// FilterProgramFromEngine is NewFilterProgramFromCEL compiling with an
+// existing engine, so that long-lived callers build the CEL environment once.
+// `ctx` is passed to the engine's instrumentation.
+func FilterProgramFromEngine(ctx context.Context, engine *filter.Engine, exprs []string) (*filter.Program, error) {
+	if len(exprs) == 0 {
+		return filter.NewProgramFromCondition(engine.Schema(), &filter.ConstantCondition{Value: false}), nil
+	}
 
 	roleConds := make([]filter.Condition, 0, len(exprs))
 	for i, rawExpr := range exprs {
@@ -128,7 +139,7 @@ func NewFilterProgramFromCEL(
 		if err != nil {
 			return nil, fmt.Errorf("expr %d: %w", i, err)
 		}
-		program, err := engine.Compile(expr)
+		program, err := engine.CompileContext(ctx, expr)
 		if err != nil {
 			return nil, fmt.Errorf("expr %d: %w", i, err)
 		}
@@ -138,14 +149,18 @@ func NewFilterProgramFromCEL(
 	cond := filter.CondOr(roleConds...)
 
 	if extra := strings.TrimSpace(engine.ExtraFilterCEL()); extra != "" {
-		extraProg, err := engine.Compile(extra)
+		extraProg, err := engine.CompileContext(ctx, extra)
 		if err != nil {
 			return nil, err
 		}
 		cond = filter.CondAnd(cond, extraProg.ConditionTree())
 	}
 
-	return filter.NewProgramFromCondition(schema, cond), nil
+	program := filter.NewProgramFromCondition(engine.Schema(), cond)
+	if inst := engine.Instrumentation(); inst != nil {
+		program = program.WithInstrumentation(inst)
+	}
+	return program, nil
 }
 
 func buildSingleRoleExpr[T comparable](
@@ -208,4 +208,19 @@ func TestNewFilterProgramFromCEL(t *testing.T) {
 	if len(stmt.Args) != 2 || stmt.Args[0] != int64(1) || stmt.Args[1] != "PUBLIC" {
 		t.Fatalf("unexpected args: %#v", stmt.Args)
 	}
+
+	engine, err := filter.NewEngine(testFilterSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		program, err := FilterProgramFromEngine(context.Background(), engine, exprs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := program.RenderSQL(filter.Bindings{"current_user_id": int64(1)}, filter.RenderOptions{Dialect: filter.DialectPostgres})
+		if err != nil || again.SQL != wantSQL {
+			t.Fatalf("the reused engine should render %q, got %q, %v", wantSQL, again.SQL, err)
+		}
+	}
 }
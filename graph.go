@@ -0,0 +1,235 @@
+package gorbac
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type graphConfig struct {
+	direct    bool
+	effective bool
+	highlight []any
+}
+
+// GraphOption customizes WriteDOT and WriteMermaid.
+type GraphOption func(*graphConfig)
+
+// WithGraphPermissions lists the permissions assigned directly to each role.
+func WithGraphPermissions() GraphOption {
+	return func(cfg *graphConfig) {
+		cfg.direct = true
+	}
+}
+
+// WithGraphEffectivePermissions lists the permissions assigned directly to
+// each role and, separately, the ones it inherits.
+func WithGraphEffectivePermissions() GraphOption {
+	return func(cfg *graphConfig) {
+		cfg.direct = true
+		cfg.effective = true
+	}
+}
+
+// WithGraphHighlight highlights the roles of `path` and the inheritance edges
+// between consecutive roles, e.g. the path returned by GrantPath.
+func WithGraphHighlight[R comparable](path ...R) GraphOption {
+	return func(cfg *graphConfig) {
+		cfg.highlight = make([]any, len(path))
+		for i, id := range path {
+			cfg.highlight[i] = id
+		}
+	}
+}
+
+type graphNode struct {
+	label       string
+	direct      []string
+	inherited   []string
+	highlighted bool
+}
+
+type graphEdge struct {
+	from, to    int
+	highlighted bool
+}
+
+// collectGraph returns the roles sorted by label and the edges from each
+// role to its parents.
+func collectGraph[R, P comparable](ctx context.Context, rbac RBACOf[R, P], opts []GraphOption) ([]graphNode, []graphEdge, error) {
+	cfg := &graphConfig{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	type entry struct {
+		id      R
+		role    RoleOf[R, P]
+		parents []R
+	}
+	var entries []entry
+	err := Walk(ctx, rbac, func(role RoleOf[R, P], parents []R) error {
+		entries = append(entries, entry{id: role.ID(), role: role, parents: parents})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return cmp.Compare(fmt.Sprint(a.id), fmt.Sprint(b.id))
+	})
+
+	index := make(map[R]int, len(entries))
+	nodes := make([]graphNode, len(entries))
+	for i, e := range entries {
+		index[e.id] = i
+		nodes[i].label = fmt.Sprint(e.id)
+		if !cfg.direct {
+			continue
+		}
+		direct := make(map[P]struct{})
+		for _, p := range e.role.Permissions(ctx) {
+			direct[p.ID()] = empty
+			nodes[i].direct = append(nodes[i].direct, fmt.Sprint(p.ID()))
+		}
+		slices.Sort(nodes[i].direct)
+		if !cfg.effective {
+			continue
+		}
+		perms, err := EffectivePermissions(ctx, rbac, e.id)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range perms {
+			if _, ok := direct[p.ID()]; !ok {
+				nodes[i].inherited = append(nodes[i].inherited, fmt.Sprint(p.ID()))
+			}
+		}
+		slices.Sort(nodes[i].inherited)
+	}
+
+	highlighted := make(map[[2]int]bool)
+	prev := -1
+	for _, h := range cfg.highlight {
+		id, ok := h.(R)
+		if !ok {
+			prev = -1
+			continue
+		}
+		i, ok := index[id]
+		if !ok {
+			prev = -1
+			continue
+		}
+		nodes[i].highlighted = true
+		if prev >= 0 {
+			highlighted[[2]int{prev, i}] = true
+		}
+		prev = i
+	}
+
+	var edges []graphEdge
+	for i, e := range entries {
+		var targets []int
+		for _, parent := range e.parents {
+			if j, ok := index[parent]; ok {
+				targets = append(targets, j)
+			}
+		}
+		slices.Sort(targets)
+		for _, j := range targets {
+			edges = append(edges, graphEdge{from: i, to: j, highlighted: highlighted[[2]int{i, j}]})
+		}
+	}
+	return nodes, edges, nil
+}
+
+// WriteDOT writes the role hierarchy of `rbac` as a Graphviz digraph. Edges
+// point from a role to the parents it inherits from.
+func WriteDOT[R, P comparable](ctx context.Context, w io.Writer, rbac RBACOf[R, P], opts ...GraphOption) error {
+	nodes, edges, err := collectGraph(ctx, rbac, opts)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("digraph rbac {\n\trankdir=BT;\n\tnode [shape=box];\n")
+	for i, n := range nodes {
+		lines := append([]string{n.label}, n.permissionLines()...)
+		for j := range lines {
+			lines[j] = dotEscape(lines[j])
+		}
+		fmt.Fprintf(&b, "\tn%d [label=\"%s\"", i, strings.Join(lines, `\n`))
+		if n.highlighted {
+			b.WriteString(", color=red, penwidth=2")
+		}
+		b.WriteString("];\n")
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "\tn%d -> n%d", e.from, e.to)
+		if e.highlighted {
+			b.WriteString(" [color=red, penwidth=2]")
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid writes the role hierarchy of `rbac` as a Mermaid flowchart.
+// Edges point from a role to the parents it inherits from.
+func WriteMermaid[R, P comparable](ctx context.Context, w io.Writer, rbac RBACOf[R, P], opts ...GraphOption) error {
+	nodes, edges, err := collectGraph(ctx, rbac, opts)
+	if err != nil {
+		return err
+	}
+	const style = "stroke:#d33,stroke-width:3px"
+	var b strings.Builder
+	b.WriteString("graph BT\n")
+	for i, n := range nodes {
+		lines := append([]string{n.label}, n.permissionLines()...)
+		for j := range lines {
+			lines[j] = mermaidEscape(lines[j])
+		}
+		fmt.Fprintf(&b, "\tn%d[\"%s\"]\n", i, strings.Join(lines, "<br/>"))
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "\tn%d --> n%d\n", e.from, e.to)
+	}
+	for i, n := range nodes {
+		if n.highlighted {
+			fmt.Fprintf(&b, "\tstyle n%d %s\n", i, style)
+		}
+	}
+	for i, e := range edges {
+		if e.highlighted {
+			fmt.Fprintf(&b, "\tlinkStyle %d %s\n", i, style)
+		}
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func (n graphNode) permissionLines() []string {
+	var lines []string
+	if len(n.direct) > 0 {
+		lines = append(lines, "direct: "+strings.Join(n.direct, ", "))
+	}
+	if len(n.inherited) > 0 {
+		lines = append(lines, "inherited: "+strings.Join(n.inherited, ", "))
+	}
+	return lines
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;", "\n", " ").Replace(s)
+}
@@ -0,0 +1,79 @@
+package gorbac
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func graphFixture(t *testing.T) *StdRBAC[string] {
+	ctx := context.Background()
+	rbac := New[string]()
+	editor, photographer, chief := NewRole("editor"), NewRole("photographer"), NewRole("chief-editor")
+	assert(t, editor.Assign(ctx, NewPermission("add-text"), NewPermission("edit-text")))
+	assert(t, photographer.Assign(ctx, NewPermission("add-photo")))
+	assert(t, chief.Assign(ctx, NewPermission(`del-"text"`)))
+	assert(t, rbac.Add(ctx, editor))
+	assert(t, rbac.Add(ctx, photographer))
+	assert(t, rbac.Add(ctx, chief))
+	assert(t, rbac.SetParents(ctx, "chief-editor", "editor", "photographer"))
+	return rbac
+}
+
+func TestWriteDOT(t *testing.T) {
+	ctx := context.Background()
+	rbac := graphFixture(t)
+	var b strings.Builder
+	assert(t, WriteDOT(ctx, &b, rbac))
+	want := `digraph rbac {
+	rankdir=BT;
+	node [shape=box];
+	n0 [label="chief-editor"];
+	n1 [label="editor"];
+	n2 [label="photographer"];
+	n0 -> n1;
+	n0 -> n2;
+}
+`
+	if b.String() != want {
+		t.Fatalf("unexpected DOT:\n%s", b.String())
+	}
+
+	path, ok := GrantPath(ctx, rbac, "chief-editor", NewPermission("add-text"))
+	if !ok {
+		t.Fatal("chief-editor should be granted add-text")
+	}
+	b.Reset()
+	assert(t, WriteDOT(ctx, &b, rbac, WithGraphEffectivePermissions(), WithGraphHighlight(path...)))
+	for _, want := range []string{
+		`n0 [label="chief-editor\ndirect: del-\"text\"\ninherited: add-photo, add-text, edit-text", color=red, penwidth=2];`,
+		`n1 [label="editor\ndirect: add-text, edit-text", color=red, penwidth=2];`,
+		`n2 [label="photographer\ndirect: add-photo"];`,
+		`n0 -> n1 [color=red, penwidth=2];`,
+		"n0 -> n2;",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("%q expected in:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	ctx := context.Background()
+	rbac := graphFixture(t)
+	var b strings.Builder
+	assert(t, WriteMermaid(ctx, &b, rbac, WithGraphPermissions(), WithGraphHighlight("chief-editor", "photographer")))
+	want := `graph BT
+	n0["chief-editor<br/>direct: del-#quot;text#quot;"]
+	n1["editor<br/>direct: add-text, edit-text"]
+	n2["photographer<br/>direct: add-photo"]
+	n0 --> n1
+	n0 --> n2
+	style n0 stroke:#d33,stroke-width:3px
+	style n2 stroke:#d33,stroke-width:3px
+	linkStyle 1 stroke:#d33,stroke-width:3px
+`
+	if b.String() != want {
+		t.Fatalf("unexpected Mermaid:\n%s", b.String())
+	}
+}
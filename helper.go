@@ -3,13 +3,17 @@ package gorbac
 import (
 	"context"
 	"fmt"
+	"slices"
 )
 
-// WalkHandler is a function defined by user to handle role
-type WalkHandler[T comparable] func(Role[T], []T) error
+// WalkHandlerOf is a function defined by user to handle role
+type WalkHandlerOf[R, P comparable] func(RoleOf[R, P], []R) error
+
+// WalkHandler is a WalkHandlerOf where role IDs and permission IDs share the type T.
+type WalkHandler[T comparable] = WalkHandlerOf[T, T]
 
 // Walk passes each Role to WalkHandler
-func Walk[T comparable](ctx context.Context, rbac RBAC[T], h WalkHandler[T]) (err error) {
+func Walk[R, P comparable](ctx context.Context, rbac RBACOf[R, P], h WalkHandlerOf[R, P]) (err error) {
 	if h == nil {
 		return
 	}
@@ -30,9 +34,9 @@ func Walk[T comparable](ctx context.Context, rbac RBAC[T], h WalkHandler[T]) (er
 }
 
 // InherCircle returns an error when detecting any circle inheritance.
-func InherCircle[T comparable](ctx context.Context, rbac RBAC[T]) (err error) {
-	skipped := make(map[T]struct{})
-	var stack []T
+func InherCircle[R, P comparable](ctx context.Context, rbac RBACOf[R, P]) (err error) {
+	skipped := make(map[R]struct{})
+	var stack []R
 
 	for _, id := range rbac.RoleIDs(ctx) {
 		if err = dfs(ctx, rbac, id, skipped, stack); err != nil {
@@ -47,8 +51,8 @@ var (
 )
 
 // https://en.wikipedia.org/wiki/Depth-first_search
-func dfs[T comparable](ctx context.Context, rbac RBAC[T], id T, skipped map[T]struct{},
-	stack []T) error {
+func dfs[R, P comparable](ctx context.Context, rbac RBACOf[R, P], id R, skipped map[R]struct{},
+	stack []R) error {
 	if _, ok := skipped[id]; ok {
 		return nil
 	}
@@ -75,14 +79,21 @@ func dfs[T comparable](ctx context.Context, rbac RBAC[T], id T, skipped map[T]st
 }
 
 // AnyGranted checks whether the role set grants any specified permission.
-func AnyGranted[T comparable](ctx context.Context, rbac RBAC[T], roles []T,
-	permissions ...Permission[T]) (ok bool) {
+// Every check goes through rbac.IsGranted. When rbac logs decisions, one
+// OpAnyGranted decision is logged for the whole call.
+func AnyGranted[R, P comparable](ctx context.Context, rbac RBACOf[R, P], roles []R,
+	permissions ...Permission[P]) (ok bool) {
+	checkCtx := ctx
+	if dl, logged := rbac.(decisionLogged[R, P]); logged {
+		checkCtx = withoutDecisionLog(ctx)
+		defer func() { dl.logDecision(ctx, OpAnyGranted, roles, permissions, ok) }()
+	}
 	if len(roles) == 0 || len(permissions) == 0 {
 		return false
 	}
 	for _, permission := range permissions {
 		for _, role := range roles {
-			if rbac.IsGranted(ctx, role, permission) {
+			if rbac.IsGranted(checkCtx, role, permission) {
 				return true
 			}
 		}
@@ -91,15 +102,22 @@ func AnyGranted[T comparable](ctx context.Context, rbac RBAC[T], roles []T,
 }
 
 // AllGranted checks whether the role set grants all specified permissions.
-func AllGranted[T comparable](ctx context.Context, rbac RBAC[T], roles []T,
-	permissions ...Permission[T]) (ok bool) {
+// Every check goes through rbac.IsGranted. When rbac logs decisions, one
+// OpAllGranted decision is logged for the whole call.
+func AllGranted[R, P comparable](ctx context.Context, rbac RBACOf[R, P], roles []R,
+	permissions ...Permission[P]) (ok bool) {
+	checkCtx := ctx
+	if dl, logged := rbac.(decisionLogged[R, P]); logged {
+		checkCtx = withoutDecisionLog(ctx)
+		defer func() { dl.logDecision(ctx, OpAllGranted, roles, permissions, ok) }()
+	}
 	if len(roles) == 0 || len(permissions) == 0 {
 		return false
 	}
 	for _, permission := range permissions {
 		granted := false
 		for _, role := range roles {
-			if rbac.IsGranted(ctx, role, permission) {
+			if rbac.IsGranted(checkCtx, role, permission) {
 				granted = true
 				break
 			}
@@ -110,3 +128,169 @@ func AllGranted[T comparable](ctx context.Context, rbac RBAC[T], roles []T,
 	}
 	return true
 }
+
+// BatchGranter is implemented by RBAC instances which can test many
+// permissions against a role set in one call. BatchGranted must agree with
+// IsGranted.
+//
+// A type embedding a BatchGranter such as *StdRBAC and overriding IsGranted
+// MUST override BatchGranted as well: the promoted method answers from the
+// embedded RBAC, bypassing the override.
+type BatchGranter[R, P comparable] interface {
+	BatchGranted(ctx context.Context, roles []R, permissions ...Permission[P]) []bool
+}
+
+// BatchGranted checks each specified permission against the role set.
+// The i-th result reports whether the role set grants `permissions[i]`.
+//
+// RBAC instances implementing BatchGranter answer in a single call,
+// others fall back to one IsGranted per role and permission.
+func BatchGranted[R, P comparable](ctx context.Context, rbac RBACOf[R, P], roles []R,
+	permissions ...Permission[P]) []bool {
+	if b, ok := rbac.(BatchGranter[R, P]); ok {
+		return b.BatchGranted(ctx, roles, permissions...)
+	}
+	result := make([]bool, len(permissions))
+	for i, permission := range permissions {
+		for _, role := range roles {
+			if rbac.IsGranted(ctx, role, permission) {
+				result[i] = true
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Checker is implemented by RBAC instances which may fail while checking,
+// e.g. ones backed by a database or a remote service. Check must agree with
+// IsGranted when it reports no error.
+//
+// A type embedding a Checker such as *StdRBAC and overriding IsGranted MUST
+// override Check as well: the promoted method answers from the embedded
+// RBAC, bypassing the override.
+type Checker[R, P comparable] interface {
+	Check(ctx context.Context, roleID R, permission Permission[P]) (bool, error)
+}
+
+// Check tests if the role has the permission and reports any error of the
+// underlying RBAC. RBAC instances not implementing Checker fall back to
+// IsGranted, with only the `ctx` error reported.
+func Check[R, P comparable](ctx context.Context, rbac RBACOf[R, P], roleID R,
+	permission Permission[P]) (bool, error) {
+	if c, ok := rbac.(Checker[R, P]); ok {
+		return c.Check(ctx, roleID, permission)
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return rbac.IsGranted(ctx, roleID, permission), nil
+}
+
+// CheckAny checks whether the role set grants any specified permission.
+// It stops at the first error, including cancellation of `ctx`.
+func CheckAny[R, P comparable](ctx context.Context, rbac RBACOf[R, P], roles []R,
+	permissions ...Permission[P]) (bool, error) {
+	if len(roles) == 0 || len(permissions) == 0 {
+		return false, nil
+	}
+	for _, permission := range permissions {
+		for _, role := range roles {
+			ok, err := Check(ctx, rbac, role, permission)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// CheckAll checks whether the role set grants all specified permissions.
+// It stops at the first error, including cancellation of `ctx`.
+func CheckAll[R, P comparable](ctx context.Context, rbac RBACOf[R, P], roles []R,
+	permissions ...Permission[P]) (bool, error) {
+	if len(roles) == 0 || len(permissions) == 0 {
+		return false, nil
+	}
+	for _, permission := range permissions {
+		granted := false
+		for _, role := range roles {
+			ok, err := Check(ctx, rbac, role, permission)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// EffectivePermissions returns the permissions of the role `id` together with
+// every permission inherited from its ancestors. A permission assigned to a
+// role shadows an inherited permission with the same ID.
+func EffectivePermissions[R, P comparable](ctx context.Context, rbac RBACOf[R, P], id R) ([]Permission[P], error) {
+	if _, err := rbac.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	closure, _ := collectRoleClosure(ctx, rbac, id)
+	seen := make(map[P]struct{})
+	var result []Permission[P]
+	for _, role := range closure {
+		for _, p := range role.Permissions(ctx) {
+			if _, ok := seen[p.ID()]; ok {
+				continue
+			}
+			seen[p.ID()] = empty
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// GrantPath explains a decision: it returns the shortest inheritance path
+// from the role `id` to a role permitting `p`, both included, and whether
+// such a role exists. Implications bound to a StdRBAC are not considered.
+func GrantPath[R, P comparable](ctx context.Context, rbac RBACOf[R, P], id R, p Permission[P]) ([]R, bool) {
+	if p == nil {
+		return nil, false
+	}
+	from := map[R]R{id: id}
+	queue := []R{id}
+	for len(queue) > 0 {
+		rid := queue[0]
+		queue = queue[1:]
+		role, err := rbac.Get(ctx, rid)
+		if err != nil {
+			continue
+		}
+		if role.Permit(ctx, p) {
+			path := []R{rid}
+			for rid != id {
+				rid = from[rid]
+				path = append(path, rid)
+			}
+			slices.Reverse(path)
+			return path, true
+		}
+		parents, err := rbac.GetParents(ctx, rid)
+		if err != nil {
+			continue
+		}
+		for _, parent := range parents {
+			if _, ok := from[parent]; !ok {
+				from[parent] = rid
+				queue = append(queue, parent)
+			}
+		}
+	}
+	return nil, false
+}
@@ -3,6 +3,7 @@ package gorbac
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
 )
 
@@ -168,3 +169,183 @@ func BenchmarkInherNormal(b *testing.B) {
 		_ = InherCircle(ctx, rbac)
 	}
 }
+
+func TestBatchGranted(t *testing.T) {
+	ctx := context.Background()
+	rbac := New[string]()
+	rA, rB, rC := NewRole("role-a"), NewRole("role-b"), NewRole("role-c")
+	assert(t, rA.Assign(ctx, pA))
+	assert(t, rB.Assign(ctx, pB))
+	assert(t, rC.Assign(ctx, pC))
+	assert(t, rbac.Add(ctx, rA))
+	assert(t, rbac.Add(ctx, rB))
+	assert(t, rbac.Add(ctx, rC))
+	assert(t, rbac.SetParents(ctx, "role-a", "role-b"))
+
+	perms := []Permission[string]{pA, pB, pC, pNone, permissionZero}
+	expected := []bool{true, true, false, false, false}
+	wrapped := struct{ RBAC[string] }{rbac}
+	for _, r := range []RBAC[string]{rbac, wrapped} {
+		got := BatchGranted(ctx, r, []string{"role-a", "not-exist"}, perms...)
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Fatalf("%T: [%d] expected %v, but %v got", r, i, expected[i], got[i])
+			}
+		}
+	}
+}
+
+type failingRBAC struct {
+	RBAC[string]
+	err error
+}
+
+func (r failingRBAC) Check(context.Context, string, Permission[string]) (bool, error) {
+	return false, r.err
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	rbac := New[string]()
+	rA := NewRole("role-a")
+	assert(t, rA.Assign(ctx, pA))
+	assert(t, rbac.Add(ctx, rA))
+	roles := []string{"role-a"}
+
+	if ok, err := CheckAll(ctx, rbac, roles, pA); err != nil || !ok {
+		t.Fatalf("role-a should have %s: %v", pA, err)
+	}
+	if ok, err := CheckAny(ctx, rbac, roles, pB, pNone); err != nil || ok {
+		t.Fatalf("role-a should not have %s or %s: %v", pB, pNone, err)
+	}
+	if ok, err := Check(ctx, struct{ RBAC[string] }{rbac}, "role-a", pA); err != nil || !ok {
+		t.Fatalf("role-a should have %s through IsGranted: %v", pA, err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := rbac.Check(canceled, "role-a", pA); !errors.Is(err, context.Canceled) {
+		t.Fatalf("%s needed, but %v got", context.Canceled, err)
+	}
+	if _, err := CheckAny(canceled, struct{ RBAC[string] }{rbac}, roles, pA); !errors.Is(err, context.Canceled) {
+		t.Fatalf("%s needed, but %v got", context.Canceled, err)
+	}
+
+	failing := failingRBAC{rbac, errors.New("backend unavailable")}
+	if ok, err := CheckAll(ctx, failing, roles, pA); err != failing.err || ok {
+		t.Fatalf("backend error needed, but %v got", err)
+	}
+}
+
+// denyingRBAC embeds a StdRBAC and overrides IsGranted with a deny-list.
+type denyingRBAC struct {
+	*StdRBAC[string]
+	denied Permission[string]
+}
+
+func (r denyingRBAC) IsGranted(ctx context.Context, id string, p Permission[string]) bool {
+	return !p.Match(r.denied) && r.StdRBAC.IsGranted(ctx, id, p)
+}
+
+// BatchGranted overrides the promoted StdRBAC.BatchGranted, see BatchGranter.
+func (r denyingRBAC) BatchGranted(ctx context.Context, roles []string, permissions ...Permission[string]) []bool {
+	result := r.StdRBAC.BatchGranted(ctx, roles, permissions...)
+	for i, p := range permissions {
+		if p != nil && p.Match(r.denied) {
+			result[i] = false
+		}
+	}
+	return result
+}
+
+// Check overrides the promoted StdRBAC.Check, see Checker.
+func (r denyingRBAC) Check(ctx context.Context, id string, p Permission[string]) (bool, error) {
+	ok, err := r.StdRBAC.Check(ctx, id, p)
+	return ok && !p.Match(r.denied), err
+}
+
+func TestGrantedEmbeddedOverride(t *testing.T) {
+	ctx := context.Background()
+	std := New[string]()
+	rA := NewRole("role-a")
+	assert(t, rA.Assign(ctx, pA, pB))
+	assert(t, std.Add(ctx, rA))
+	var decisions []Decision[string, string]
+	std.SetDecisionLogger(DecisionLoggerFunc[string, string](func(_ context.Context, d Decision[string, string]) {
+		decisions = append(decisions, d)
+	}))
+
+	rbac := denyingRBAC{std, pA}
+	roles := []string{"role-a"}
+	if AnyGranted(ctx, rbac, roles, pA) || AllGranted(ctx, rbac, roles, pA, pB) {
+		t.Fatal("AnyGranted and AllGranted should go through the overriding IsGranted")
+	}
+	if !AnyGranted(ctx, rbac, roles, pA, pB) {
+		t.Fatalf("role-a should have %s", pB)
+	}
+	if got := BatchGranted(ctx, rbac, roles, pA, pB); !slices.Equal(got, []bool{false, true}) {
+		t.Fatalf("BatchGranted should agree with IsGranted, got %v", got)
+	}
+	if len(decisions) != 4 || decisions[0].Op != OpAnyGranted || decisions[1].Op != OpAllGranted ||
+		decisions[0].Granted || !decisions[2].Granted || decisions[3].Op != OpBatchGranted {
+		t.Fatalf("one decision per call expected, got %+v", decisions)
+	}
+	if ok, err := CheckAny(ctx, rbac, roles, pA); ok || err != nil {
+		t.Fatalf("Check should agree with IsGranted, got %v, %v", ok, err)
+	}
+}
+
+func TestEffectivePermissions(t *testing.T) {
+	ctx := context.Background()
+	rbac := New[string]()
+	rA, rB := NewRole("role-a"), NewRole("role-b")
+	assert(t, rA.Assign(ctx, pA, NewFilterPermission("permission-b", "creator_id == 1")))
+	assert(t, rB.Assign(ctx, pB, pC))
+	assert(t, rbac.Add(ctx, rA))
+	assert(t, rbac.Add(ctx, rB))
+	assert(t, rbac.SetParents(ctx, "role-a", "role-b"))
+
+	perms, err := EffectivePermissions(ctx, rbac, "role-a")
+	assert(t, err)
+	if len(perms) != 3 {
+		t.Fatalf("3 effective permissions expected, but %d got", len(perms))
+	}
+	for _, p := range perms {
+		if p.ID() == "permission-b" {
+			if _, ok := p.(FilterPermission[string]); !ok {
+				t.Fatal("the own permission-b should shadow the inherited one")
+			}
+		}
+	}
+	if _, err := EffectivePermissions(ctx, rbac, "not-exist"); err != ErrRoleNotExist {
+		t.Fatalf("%s needed", ErrRoleNotExist)
+	}
+}
+
+func TestGrantPath(t *testing.T) {
+	ctx := context.Background()
+	rbac := New[string]()
+	rA, rB, rC := NewRole("role-a"), NewRole("role-b"), NewRole("role-c")
+	assert(t, rA.Assign(ctx, pA))
+	assert(t, rB.Assign(ctx, pB))
+	assert(t, rC.Assign(ctx, pC))
+	assert(t, rbac.Add(ctx, rA))
+	assert(t, rbac.Add(ctx, rB))
+	assert(t, rbac.Add(ctx, rC))
+	assert(t, rbac.SetParents(ctx, "role-a", "role-b"))
+	assert(t, rbac.SetParents(ctx, "role-b", "role-c"))
+	assert(t, rbac.SetParents(ctx, "role-c", "role-a"))
+
+	if path, ok := GrantPath(ctx, rbac, "role-a", pC); !ok || !slices.Equal(path, []string{"role-a", "role-b", "role-c"}) {
+		t.Fatalf("unexpected path %v", path)
+	}
+	if path, ok := GrantPath(ctx, rbac, "role-a", pA); !ok || !slices.Equal(path, []string{"role-a"}) {
+		t.Fatalf("unexpected path %v", path)
+	}
+	if _, ok := GrantPath(ctx, rbac, "role-a", pNone); ok {
+		t.Fatal("permission-none should not be granted")
+	}
+	if _, ok := GrantPath(ctx, rbac, "not-exist", pA); ok {
+		t.Fatal("a missing role grants nothing")
+	}
+}
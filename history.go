@@ -0,0 +1,528 @@
+package gorbac
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrVersionNotExist occurred if a version is not in the change log
+var ErrVersionNotExist = errors.New("Version does not exist")
+
+// Mutations recorded in Event.Op.
+const (
+	EventAddRole       = "AddRole"
+	EventRemoveRole    = "RemoveRole"
+	EventSetParents    = "SetParents"
+	EventRemoveParents = "RemoveParents"
+	EventAssign        = "Assign"
+	EventRevoke        = "Revoke"
+)
+
+// Event records one mutation of a role model. Version 0 events describe the
+// state a log starts from; later versions are numbered from 1.
+type Event[R, P comparable] struct {
+	Version uint64    `json:"version"`
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor,omitempty"`
+	Op      string    `json:"op"`
+	Role    R         `json:"role"`
+	Parents []R       `json:"parents,omitempty"`
+	// Permissions are assigned or revoked; for EventAddRole, the
+	// permissions the role is added with.
+	Permissions []PermissionRecord[P] `json:"permissions,omitempty"`
+}
+
+func (e Event[R, P]) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "v%d %s", e.Version, e.Op)
+	if e.Actor != "" {
+		fmt.Fprintf(&b, " by %s", e.Actor)
+	}
+	fmt.Fprintf(&b, ": %v", e.Role)
+	var args []string
+	for _, parent := range e.Parents {
+		args = append(args, fmt.Sprint(parent))
+	}
+	for _, p := range e.Permissions {
+		args = append(args, formatPermissionRecord(p))
+	}
+	if len(args) > 0 {
+		b.WriteString(" " + strings.Join(args, ", "))
+	}
+	return b.String()
+}
+
+func formatPermissionRecord[P comparable](r PermissionRecord[P]) string {
+	s := fmt.Sprint(r.ID)
+	if r.Sep != "" {
+		s += " (sep: " + r.Sep + ")"
+	}
+	if r.Filter != "" {
+		s += " (filter: " + r.Filter + ")"
+	}
+	return s
+}
+
+// ApplyEvent performs the mutation described by `e` on `rbac`. Roles are
+// added as NewRoleOf roles.
+func ApplyEvent[R, P comparable](ctx context.Context, rbac RBACOf[R, P], e Event[R, P]) error {
+	perms := make([]Permission[P], 0, len(e.Permissions))
+	for _, record := range e.Permissions {
+		p, err := record.Permission()
+		if err != nil {
+			return err
+		}
+		perms = append(perms, p)
+	}
+	switch e.Op {
+	case EventAddRole:
+		role := NewRoleOf[R, P](e.Role)
+		if err := role.Assign(ctx, perms...); err != nil {
+			return err
+		}
+		return rbac.Add(ctx, role)
+	case EventRemoveRole:
+		return rbac.Remove(ctx, e.Role)
+	case EventSetParents:
+		return rbac.SetParents(ctx, e.Role, e.Parents...)
+	case EventRemoveParents:
+		return rbac.RemoveParents(ctx, e.Role, e.Parents...)
+	case EventAssign, EventRevoke:
+		role, err := rbac.Get(ctx, e.Role)
+		if err != nil {
+			return err
+		}
+		if e.Op == EventAssign {
+			return role.Assign(ctx, perms...)
+		}
+		return role.Revoke(ctx, perms...)
+	default:
+		return fmt.Errorf("unknown event op %q", e.Op)
+	}
+}
+
+// roleState is the serialisable state of a role.
+type roleState[R, P comparable] struct {
+	perms   map[P]PermissionRecord[P]
+	parents map[R]struct{}
+}
+
+func snapshotState[R, P comparable](ctx context.Context, rbac RBACOf[R, P]) (map[R]roleState[R, P], error) {
+	state := make(map[R]roleState[R, P])
+	err := Walk(ctx, rbac, func(role RoleOf[R, P], parents []R) error {
+		s := roleState[R, P]{perms: make(map[P]PermissionRecord[P]), parents: make(map[R]struct{})}
+		for _, p := range role.Permissions(ctx) {
+			record, err := RecordOf(p)
+			if err != nil {
+				return fmt.Errorf("%v: %w", role.ID(), err)
+			}
+			s.perms[p.ID()] = record
+		}
+		for _, parent := range parents {
+			s.parents[parent] = empty
+		}
+		state[role.ID()] = s
+		return nil
+	})
+	return state, err
+}
+
+// stateEvents returns version 0 events rebuilding `state`: every role first,
+// then the parents.
+func stateEvents[R, P comparable](state map[R]roleState[R, P]) []Event[R, P] {
+	ids := sortedKeys(state)
+	events := make([]Event[R, P], 0, len(ids))
+	for _, id := range ids {
+		events = append(events, Event[R, P]{Op: EventAddRole, Role: id, Permissions: sortedRecords(state[id].perms)})
+	}
+	for _, id := range ids {
+		if parents := sortedKeys(state[id].parents); len(parents) > 0 {
+			events = append(events, Event[R, P]{Op: EventSetParents, Role: id, Parents: parents})
+		}
+	}
+	return events
+}
+
+// DiffEvents returns the version 0 events turning the role model of `from`
+// into the one of `to`, e.g. to sync a replica with ApplyEvent.
+func DiffEvents[R, P comparable](ctx context.Context, from, to RBACOf[R, P]) ([]Event[R, P], error) {
+	fromState, err := snapshotState(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	toState, err := snapshotState(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	return diffEvents(fromState, toState), nil
+}
+
+// diffEvents returns the events turning the state `from` into `to`.
+func diffEvents[R, P comparable](from, to map[R]roleState[R, P]) []Event[R, P] {
+	var events []Event[R, P]
+	for _, id := range sortedKeys(from) {
+		if _, ok := to[id]; !ok {
+			events = append(events, Event[R, P]{Op: EventRemoveRole, Role: id})
+		}
+	}
+	ids := sortedKeys(to)
+	for _, id := range ids {
+		old, ok := from[id]
+		if !ok {
+			events = append(events, Event[R, P]{Op: EventAddRole, Role: id, Permissions: sortedRecords(to[id].perms)})
+			continue
+		}
+		revoked := make(map[P]PermissionRecord[P])
+		assigned := make(map[P]PermissionRecord[P])
+		for pid, record := range old.perms {
+			if r, ok := to[id].perms[pid]; !ok || r != record {
+				revoked[pid] = record
+			}
+		}
+		for pid, record := range to[id].perms {
+			if r, ok := old.perms[pid]; !ok || r != record {
+				assigned[pid] = record
+			}
+		}
+		if len(revoked) > 0 {
+			events = append(events, Event[R, P]{Op: EventRevoke, Role: id, Permissions: sortedRecords(revoked)})
+		}
+		if len(assigned) > 0 {
+			events = append(events, Event[R, P]{Op: EventAssign, Role: id, Permissions: sortedRecords(assigned)})
+		}
+	}
+	for _, id := range ids {
+		var removed, added []R
+		for parent := range from[id].parents {
+			_, kept := to[id].parents[parent]
+			if _, exists := to[parent]; !kept && exists {
+				removed = append(removed, parent)
+			}
+		}
+		for parent := range to[id].parents {
+			if _, ok := from[id].parents[parent]; !ok {
+				added = append(added, parent)
+			}
+		}
+		if len(removed) > 0 {
+			events = append(events, Event[R, P]{Op: EventRemoveParents, Role: id, Parents: sortIDs(removed)})
+		}
+		if len(added) > 0 {
+			events = append(events, Event[R, P]{Op: EventSetParents, Role: id, Parents: sortIDs(added)})
+		}
+	}
+	return events
+}
+
+func sortedKeys[K comparable, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return sortIDs(keys)
+}
+
+func sortIDs[T comparable](ids []T) []T {
+	slices.SortFunc(ids, func(a, b T) int { return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b)) })
+	return ids
+}
+
+func sortedRecords[P comparable](perms map[P]PermissionRecord[P]) []PermissionRecord[P] {
+	records := make([]PermissionRecord[P], 0, len(perms))
+	for _, id := range sortedKeys(perms) {
+		records = append(records, perms[id])
+	}
+	return records
+}
+
+type historyConfig struct {
+	actor func(context.Context) string
+	now   func() time.Time
+}
+
+// HistoryOption customizes VersionedRBACOf construction.
+type HistoryOption func(*historyConfig)
+
+// WithHistoryActor extracts the actor recorded with every event from the
+// mutating context. By default the subject attached by WithSubject is
+// recorded.
+func WithHistoryActor(actor func(context.Context) string) HistoryOption {
+	return func(cfg *historyConfig) {
+		cfg.actor = actor
+	}
+}
+
+// VersionedRBACOf keeps an event-sourced history of a StdRBACOf.
+//
+// Every mutation made through it, including Assign and Revoke on the roles
+// returned by Get, is applied to the wrapped RBAC and appended to a
+// versioned change log with the actor taken from the context. The log
+// allows materializing the role model at any version and rolling back.
+//
+// Add stores a copy of the role: a NewRoleOf role rebuilt from the
+// PermissionRecord form of its permissions, so that the log fully describes
+// the state. Custom role types and role implications are not kept, and
+// custom permission types are rejected with ErrUnsupportedPermission. Use
+// Get to change a role after adding it; mutations made on the added object,
+// or directly on the wrapped RBAC, are neither applied nor recorded.
+type VersionedRBACOf[R, P comparable] struct {
+	mutex   sync.RWMutex
+	rbac    *StdRBACOf[R, P]
+	cfg     historyConfig
+	initial []Event[R, P]
+	events  []Event[R, P]
+}
+
+// VersionedRBAC is a VersionedRBACOf where role IDs and permission IDs share the type T.
+type VersionedRBAC[T comparable] = VersionedRBACOf[T, T]
+
+// NewVersioned records the mutations of `rbac`. Its current state is
+// version 0.
+func NewVersioned[R, P comparable](ctx context.Context, rbac *StdRBACOf[R, P], opts ...HistoryOption) (*VersionedRBACOf[R, P], error) {
+	cfg := historyConfig{actor: subjectString, now: time.Now}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+	state, err := snapshotState(ctx, rbac)
+	if err != nil {
+		return nil, err
+	}
+	return &VersionedRBACOf[R, P]{rbac: rbac, cfg: cfg, initial: stateEvents(state)}, nil
+}
+
+// Version returns the version of the latest event, 0 before any mutation.
+func (v *VersionedRBACOf[R, P]) Version() uint64 {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return uint64(len(v.events))
+}
+
+// Events returns the events after version `from`, oldest first.
+func (v *VersionedRBACOf[R, P]) Events(from uint64) []Event[R, P] {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	if from >= uint64(len(v.events)) {
+		return nil
+	}
+	return slices.Clone(v.events[from:])
+}
+
+// Initial returns the version 0 events rebuilding the state the history
+// started from.
+func (v *VersionedRBACOf[R, P]) Initial() []Event[R, P] {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return slices.Clone(v.initial)
+}
+
+// Snapshot returns the current version and the version 0 events
+// rebuilding the role model at that version.
+func (v *VersionedRBACOf[R, P]) Snapshot(ctx context.Context) (uint64, []Event[R, P], error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	state, err := snapshotState(ctx, v.rbac)
+	if err != nil {
+		return 0, nil, err
+	}
+	return uint64(len(v.events)), stateEvents(state), nil
+}
+
+// At materializes the role model as it was at `version` into a new RBAC.
+func (v *VersionedRBACOf[R, P]) At(ctx context.Context, version uint64) (*StdRBACOf[R, P], error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+	return v.at(ctx, version)
+}
+
+func (v *VersionedRBACOf[R, P]) at(ctx context.Context, version uint64) (*StdRBACOf[R, P], error) {
+	if version > uint64(len(v.events)) {
+		return nil, ErrVersionNotExist
+	}
+	rbac := NewOf[R, P]()
+	for _, e := range slices.Concat(v.initial, v.events[:version]) {
+		if err := ApplyEvent(ctx, rbac, e); err != nil {
+			return nil, fmt.Errorf("version %d: %w", e.Version, err)
+		}
+	}
+	return rbac, nil
+}
+
+// Rollback restores the role model as it was at `version`. The rollback is
+// itself recorded as new events, keeping the log append-only.
+//
+// The events are applied to a copy of the current role model, which then
+// replaces it at once, so a failing rollback leaves the RBAC unchanged.
+// Roles the rollback does not change are kept as they are.
+func (v *VersionedRBACOf[R, P]) Rollback(ctx context.Context, version uint64) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	target, err := v.at(ctx, version)
+	if err != nil {
+		return err
+	}
+	current, err := snapshotState(ctx, v.rbac)
+	if err != nil {
+		return err
+	}
+	targetState, err := snapshotState(ctx, target)
+	if err != nil {
+		return err
+	}
+	clone := NewOf[R, P]()
+	for _, e := range stateEvents(current) {
+		if err := ApplyEvent(ctx, clone, e); err != nil {
+			return err
+		}
+	}
+	events := diffEvents(current, targetState)
+	changed := make(map[R]struct{})
+	for _, e := range events {
+		if err := ApplyEvent(ctx, clone, e); err != nil {
+			return err
+		}
+		if e.Op != EventSetParents && e.Op != EventRemoveParents {
+			changed[e.Role] = empty
+		}
+	}
+
+	v.rbac.mutex.Lock()
+	for id := range clone.roles {
+		if _, ok := changed[id]; !ok {
+			clone.roles[id] = v.rbac.roles[id]
+		}
+	}
+	v.rbac.roles, v.rbac.parents = clone.roles, clone.parents
+	v.rbac.mutex.Unlock()
+	for _, e := range events {
+		v.append(ctx, e)
+	}
+	return nil
+}
+
+// record applies `e` and appends it to the log. The caller holds the mutex.
+func (v *VersionedRBACOf[R, P]) record(ctx context.Context, e Event[R, P]) error {
+	if err := ApplyEvent(ctx, v.rbac, e); err != nil {
+		return err
+	}
+	v.append(ctx, e)
+	return nil
+}
+
+// append stamps `e` with the next version and appends it to the log. The
+// caller holds the mutex.
+func (v *VersionedRBACOf[R, P]) append(ctx context.Context, e Event[R, P]) {
+	e.Version = uint64(len(v.events)) + 1
+	e.Time = v.cfg.now()
+	if v.cfg.actor != nil {
+		e.Actor = v.cfg.actor(ctx)
+	}
+	v.events = append(v.events, e)
+}
+
+func (v *VersionedRBACOf[R, P]) recordLocked(ctx context.Context, e Event[R, P]) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.record(ctx, e)
+}
+
+func records[P comparable](perms []Permission[P]) ([]PermissionRecord[P], error) {
+	result := make([]PermissionRecord[P], 0, len(perms))
+	for _, p := range perms {
+		record, err := RecordOf(p)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+// Add a copy of the role `r` with its current permissions. `r` itself is
+// not stored; use Get to change the role afterwards.
+func (v *VersionedRBACOf[R, P]) Add(ctx context.Context, r RoleOf[R, P]) error {
+	perms := r.Permissions(ctx)
+	rs, err := records(perms)
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(rs, func(a, b PermissionRecord[P]) int { return cmp.Compare(fmt.Sprint(a.ID), fmt.Sprint(b.ID)) })
+	return v.recordLocked(ctx, Event[R, P]{Op: EventAddRole, Role: r.ID(), Permissions: rs})
+}
+
+// Remove the role by `id`.
+func (v *VersionedRBACOf[R, P]) Remove(ctx context.Context, id R) error {
+	return v.recordLocked(ctx, Event[R, P]{Op: EventRemoveRole, Role: id})
+}
+
+// Get returns the role by `id`. Assign and Revoke on it are recorded.
+func (v *VersionedRBACOf[R, P]) Get(ctx context.Context, id R) (RoleOf[R, P], error) {
+	role, err := v.rbac.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &versionedRole[R, P]{RoleOf: role, rbac: v}, nil
+}
+
+// RoleIDs returns all role IDs.
+func (v *VersionedRBACOf[R, P]) RoleIDs(ctx context.Context) []R {
+	return v.rbac.RoleIDs(ctx)
+}
+
+// SetParents bind `parents` to the role `id`.
+func (v *VersionedRBACOf[R, P]) SetParents(ctx context.Context, id R, parents ...R) error {
+	return v.recordLocked(ctx, Event[R, P]{Op: EventSetParents, Role: id, Parents: slices.Clone(parents)})
+}
+
+// GetParents return `parents` of the role `id`.
+func (v *VersionedRBACOf[R, P]) GetParents(ctx context.Context, id R) ([]R, error) {
+	return v.rbac.GetParents(ctx, id)
+}
+
+// RemoveParents unbind `parents` from the role `id`.
+func (v *VersionedRBACOf[R, P]) RemoveParents(ctx context.Context, id R, parents ...R) error {
+	return v.recordLocked(ctx, Event[R, P]{Op: EventRemoveParents, Role: id, Parents: slices.Clone(parents)})
+}
+
+// IsGranted tests if the role `id` has permission `p`.
+func (v *VersionedRBACOf[R, P]) IsGranted(ctx context.Context, id R, p Permission[P]) bool {
+	return v.rbac.IsGranted(ctx, id, p)
+}
+
+// Check tests if the role `id` has permission `p`, see StdRBACOf.Check.
+func (v *VersionedRBACOf[R, P]) Check(ctx context.Context, id R, p Permission[P]) (bool, error) {
+	return v.rbac.Check(ctx, id, p)
+}
+
+// versionedRole records Assign and Revoke of a role of a VersionedRBACOf.
+type versionedRole[R, P comparable] struct {
+	RoleOf[R, P]
+	rbac *VersionedRBACOf[R, P]
+}
+
+func (r *versionedRole[R, P]) Assign(ctx context.Context, perms ...Permission[P]) error {
+	return r.change(ctx, EventAssign, perms)
+}
+
+func (r *versionedRole[R, P]) Revoke(ctx context.Context, perms ...Permission[P]) error {
+	return r.change(ctx, EventRevoke, perms)
+}
+
+func (r *versionedRole[R, P]) change(ctx context.Context, op string, perms []Permission[P]) error {
+	if len(perms) == 0 {
+		return nil
+	}
+	rs, err := records(perms)
+	if err != nil {
+		return err
+	}
+	return r.rbac.recordLocked(ctx, Event[R, P]{Op: op, Role: r.ID(), Permissions: rs})
+}
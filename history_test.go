@@ -0,0 +1,161 @@
+package gorbac
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestVersionedRBAC(t *testing.T) {
+	ctx := context.Background()
+	std := New[string]()
+	rA := NewRole("role-a")
+	assert(t, rA.Assign(ctx, pA))
+	assert(t, std.Add(ctx, rA))
+
+	v, err := NewVersioned(ctx, std)
+	assert(t, err)
+	var _ RBAC[string] = v
+	if v.Version() != 0 || len(v.Initial()) != 1 {
+		t.Fatalf("unexpected initial state %d, %v", v.Version(), v.Initial())
+	}
+
+	alice := WithSubject(ctx, "alice")
+	rB := NewRole("role-b")
+	assert(t, rB.Assign(ctx, pB))
+	assert(t, v.Add(alice, rB))
+	assert(t, v.SetParents(alice, "role-b", "role-a"))
+	role, err := v.Get(WithSubject(ctx, "bob"), "role-a")
+	assert(t, err)
+	bob := WithSubject(ctx, "bob")
+	assert(t, role.Assign(bob, pC, NewFilterPermission("posts", "creator_id == user.id")))
+	assert(t, role.Revoke(bob, pA))
+	if err := v.SetParents(bob, "role-b", "role-x"); err != ErrRoleNotExist {
+		t.Fatalf("%s expected, but %v got", ErrRoleNotExist, err)
+	}
+	if v.Version() != 4 {
+		t.Fatalf("version 4 expected, but %d got", v.Version())
+	}
+	if !v.IsGranted(ctx, "role-b", pC) || v.IsGranted(ctx, "role-b", pA) {
+		t.Fatal("the mutations should apply to the wrapped RBAC")
+	}
+	if !std.IsGranted(ctx, "role-b", pC) {
+		t.Fatal("the wrapped RBAC should be updated")
+	}
+
+	events := v.Events(2)
+	if len(events) != 2 || events[0].Version != 3 || events[0].Actor != "bob" || events[0].Op != EventAssign {
+		t.Fatalf("unexpected events %v", events)
+	}
+	if s := events[0].String(); s != "v3 Assign by bob: role-a permission-c, posts (filter: creator_id == user.id)" {
+		t.Fatalf("unexpected event %q", s)
+	}
+	if e := v.Events(0)[0]; e.Actor != "alice" || e.Time.IsZero() {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	old, err := v.At(ctx, 2)
+	assert(t, err)
+	if !old.IsGranted(ctx, "role-b", pA) || old.IsGranted(ctx, "role-b", pC) {
+		t.Fatal("version 2 should grant permission-a only")
+	}
+	if _, err := v.At(ctx, 5); !errors.Is(err, ErrVersionNotExist) {
+		t.Fatalf("%s expected, but %v got", ErrVersionNotExist, err)
+	}
+
+	assert(t, v.Rollback(WithSubject(ctx, "carol"), 0))
+	if v.Version() <= 4 {
+		t.Fatal("the rollback should be recorded")
+	}
+	for _, e := range v.Events(4) {
+		if e.Actor != "carol" {
+			t.Fatalf("unexpected event %v", e)
+		}
+	}
+	ids := v.RoleIDs(ctx)
+	if !slices.Equal(ids, []string{"role-a"}) || !v.IsGranted(ctx, "role-a", pA) || v.IsGranted(ctx, "role-a", pC) {
+		t.Fatalf("version 0 should be restored, got %v", ids)
+	}
+
+	version := v.Version()
+	assert(t, v.Rollback(ctx, 4))
+	if !v.IsGranted(ctx, "role-b", pC) || v.IsGranted(ctx, "role-a", pA) {
+		t.Fatal("version 4 should be restored")
+	}
+	parents, err := v.GetParents(ctx, "role-b")
+	assert(t, err)
+	if !slices.Equal(parents, []string{"role-a"}) {
+		t.Fatalf("unexpected parents %v", parents)
+	}
+	now, err := v.At(ctx, v.Version())
+	assert(t, err)
+	then, err := v.At(ctx, 4)
+	assert(t, err)
+	for _, rbac := range []*StdRBAC[string]{now, then} {
+		if !rbac.IsGranted(ctx, "role-b", pC) || rbac.IsGranted(ctx, "role-a", pA) {
+			t.Fatal("replaying the log should reach the same state")
+		}
+	}
+	if v.Version() == version {
+		t.Fatal("rolling forward should be recorded")
+	}
+}
+
+func TestApplyEvent(t *testing.T) {
+	ctx := context.Background()
+	rbac := New[string]()
+	events := []Event[string, string]{
+		{Op: EventAddRole, Role: "role-a", Permissions: []PermissionRecord[string]{{ID: "a:b", Sep: ":"}}},
+		{Op: EventAddRole, Role: "role-b"},
+		{Op: EventSetParents, Role: "role-b", Parents: []string{"role-a"}},
+	}
+	for _, e := range events {
+		assert(t, ApplyEvent(ctx, rbac, e))
+	}
+	if !rbac.IsGranted(ctx, "role-b", NewLayerPermission("a:b:c", ":")) {
+		t.Fatal("role-b should inherit the layered permission")
+	}
+	if err := ApplyEvent(ctx, rbac, Event[string, string]{Op: "Rename", Role: "role-a"}); err == nil {
+		t.Fatal("an unknown op should fail")
+	}
+	if err := ApplyEvent(ctx, rbac, Event[string, string]{Op: EventAssign, Role: "role-x"}); err != ErrRoleNotExist {
+		t.Fatalf("%s expected, but %v got", ErrRoleNotExist, err)
+	}
+}
+
+func TestVersionedRBACRollbackSwap(t *testing.T) {
+	ctx := context.Background()
+	std := New[string]()
+	imp := NewImplications[string]()
+	assert(t, imp.SetImplies(ctx, "admin", "read"))
+	rA := NewRole("role-a")
+	rA.SetImplications(imp)
+	assert(t, rA.Assign(ctx, NewPermission("admin")))
+	assert(t, std.Add(ctx, rA))
+	v, err := NewVersioned(ctx, std)
+	assert(t, err)
+
+	// Add stores a copy; the added object is not tracked
+	rB := NewRole("role-b")
+	assert(t, v.Add(ctx, rB))
+	assert(t, rB.Assign(ctx, pB))
+	if v.IsGranted(ctx, "role-b", pB) {
+		t.Fatal("changes to the added object should not apply")
+	}
+	role, err := v.Get(ctx, "role-b")
+	assert(t, err)
+	assert(t, role.Assign(ctx, pB))
+	assert(t, v.SetParents(ctx, "role-b", "role-a"))
+
+	assert(t, v.Rollback(ctx, 1))
+	if v.IsGranted(ctx, "role-b", pB) || v.IsGranted(ctx, "role-b", NewPermission("read")) {
+		t.Fatal("version 1 should be restored")
+	}
+	if !v.IsGranted(ctx, "role-a", NewPermission("read")) {
+		t.Fatal("role-a should keep its implications")
+	}
+	if got, err := std.Get(ctx, "role-a"); err != nil || got != rA {
+		t.Fatalf("a role the rollback does not change should be kept, got %v, %v", got, err)
+	}
+}
@@ -0,0 +1,42 @@
+package gorbac
+
+import (
+	"context"
+	"time"
+)
+
+// CheckEvent describes one authorization check.
+type CheckEvent struct {
+	Granted  bool
+	Duration time.Duration
+	// Depth is the deepest inheritance level walked, 1 being the checked
+	// role itself and 0 meaning the role does not exist.
+	Depth int
+}
+
+// Instrumentation receives measurements from authorization checks, so that
+// metrics and tracing backends (Prometheus, OpenTelemetry, ...) can be
+// plugged in without this package depending on them.
+//
+// Implementations are called synchronously and must be safe for concurrent use.
+type Instrumentation interface {
+	// ObserveCheck is called after every check of StdRBACOf.
+	ObserveCheck(ctx context.Context, ev CheckEvent)
+	// ObserveCache is called on every decision lookup of CachedRBACOf.
+	ObserveCache(ctx context.Context, hit bool)
+}
+
+// SetInstrumentation registers `inst` to observe every check.
+// Passing nil disables instrumentation.
+func (rbac *StdRBACOf[R, P]) SetInstrumentation(inst Instrumentation) {
+	rbac.mutex.Lock()
+	rbac.instrumentation = inst
+	rbac.mutex.Unlock()
+}
+
+// WithCacheInstrumentation reports cache hits and misses to `inst`.
+func WithCacheInstrumentation(inst Instrumentation) CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.instrumentation = inst
+	}
+}
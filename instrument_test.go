@@ -0,0 +1,63 @@
+package gorbac
+
+import (
+	"context"
+	"testing"
+)
+
+type recordingInstrumentation struct {
+	checks []CheckEvent
+	hits   int
+	misses int
+}
+
+func (r *recordingInstrumentation) ObserveCheck(_ context.Context, ev CheckEvent) {
+	r.checks = append(r.checks, ev)
+}
+
+func (r *recordingInstrumentation) ObserveCache(_ context.Context, hit bool) {
+	if hit {
+		r.hits++
+	} else {
+		r.misses++
+	}
+}
+
+func TestInstrumentation(t *testing.T) {
+	ctx := context.Background()
+	rbac := New[string]()
+	rA, rB, rC := NewRole("role-a"), NewRole("role-b"), NewRole("role-c")
+	assert(t, rC.Assign(ctx, pC))
+	assert(t, rbac.Add(ctx, rA))
+	assert(t, rbac.Add(ctx, rB))
+	assert(t, rbac.Add(ctx, rC))
+	assert(t, rbac.SetParents(ctx, "role-a", "role-b"))
+	assert(t, rbac.SetParents(ctx, "role-b", "role-c"))
+
+	inst := &recordingInstrumentation{}
+	rbac.SetInstrumentation(inst)
+	cached := NewCached[string, string](rbac, WithCacheInstrumentation(inst))
+	cached.IsGranted(ctx, "role-a", pC)
+	cached.IsGranted(ctx, "role-a", pC)
+	AnyGranted(ctx, rbac, []string{"role-b"}, pA)
+	rbac.IsGranted(ctx, "not-exist", pA)
+	rbac.BatchGranted(ctx, []string{"role-a"}, pC)
+
+	expected := []CheckEvent{
+		{Granted: true, Depth: 3},
+		{Granted: false, Depth: 2},
+		{Granted: false, Depth: 0},
+		{Granted: true, Depth: 3},
+	}
+	if len(inst.checks) != len(expected) {
+		t.Fatalf("%d checks expected, but %d got", len(expected), len(inst.checks))
+	}
+	for i, e := range expected {
+		if inst.checks[i].Granted != e.Granted || inst.checks[i].Depth != e.Depth {
+			t.Fatalf("[%d] %+v expected, but %+v got", i, e, inst.checks[i])
+		}
+	}
+	if inst.hits != 1 || inst.misses != 1 {
+		t.Fatalf("1 hit and 1 miss expected, but %d and %d got", inst.hits, inst.misses)
+	}
+}
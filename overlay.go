@@ -0,0 +1,418 @@
+package gorbac
+
+import (
+	"context"
+	"sync"
+)
+
+// OverlayRBACOf layers mutable customizations over a read-only base RBAC,
+// e.g. a shared role model with per-environment or per-customer overrides.
+//
+// Lookups, GetParents and IsGranted resolve through the overlay first and
+// fall back to the base. Mutations only change the overlay:
+//
+//   - Add and Remove add roles or hide base roles.
+//   - SetParents and RemoveParents replace the parents of a role in the
+//     overlay, starting from its base parents.
+//   - Assign and Revoke on a role returned by Get copy a base role into the
+//     overlay before changing it.
+//
+// Roles copied into the overlay keep the implication registry of their base
+// role, and IsGranted applies the implications bound to the base RBAC, so an
+// overlay without changes decides like its base.
+//
+// The base must not change while the overlay is in use.
+type OverlayRBACOf[R, P comparable] struct {
+	base  RBACOf[R, P]
+	mutex sync.RWMutex
+	// roles holds the roles added to the overlay and copies of base roles
+	roles RolesOf[R, P]
+	// parents overrides the base parents of a role
+	parents map[R]map[R]struct{}
+	// removed hides base roles
+	removed map[R]struct{}
+}
+
+// OverlayRBAC is an OverlayRBACOf where role IDs and permission IDs share the type T.
+type OverlayRBAC[T comparable] = OverlayRBACOf[T, T]
+
+// NewOverlay returns an empty overlay over `base`.
+func NewOverlay[R, P comparable](base RBACOf[R, P]) *OverlayRBACOf[R, P] {
+	return &OverlayRBACOf[R, P]{
+		base:    base,
+		roles:   make(RolesOf[R, P]),
+		parents: make(map[R]map[R]struct{}),
+		removed: make(map[R]struct{}),
+	}
+}
+
+// Base returns the underlying read-only RBAC.
+func (o *OverlayRBACOf[R, P]) Base() RBACOf[R, P] {
+	return o.base
+}
+
+// Add a role `r` to the overlay. A base role hidden by Remove can be added
+// again; it starts without parents.
+func (o *OverlayRBACOf[R, P]) Add(ctx context.Context, r RoleOf[R, P]) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	id := r.ID()
+	if o.exists(ctx, id) {
+		return ErrRoleExist
+	}
+	if _, ok := o.removed[id]; ok {
+		delete(o.removed, id)
+		o.parents[id] = make(map[R]struct{})
+	}
+	o.roles[id] = r
+	return nil
+}
+
+// Remove the role by `id`, hiding it when it comes from the base. Edges
+// from other roles to it are removed as well.
+func (o *OverlayRBACOf[R, P]) Remove(ctx context.Context, id R) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if !o.exists(ctx, id) {
+		return ErrRoleNotExist
+	}
+	for _, rid := range o.roleIDs(ctx) {
+		if rid == id {
+			continue
+		}
+		parents, err := o.parentsOf(ctx, rid)
+		if err != nil {
+			return err
+		}
+		if _, ok := parents[id]; ok {
+			parents = o.override(rid, parents)
+			delete(parents, id)
+		}
+	}
+	delete(o.roles, id)
+	delete(o.parents, id)
+	if _, err := o.base.Get(ctx, id); err == nil {
+		o.removed[id] = empty
+	}
+	return nil
+}
+
+// Get returns the role by `id`. Base roles are returned wrapped, so that
+// Assign and Revoke copy them into the overlay instead of changing the base.
+func (o *OverlayRBACOf[R, P]) Get(ctx context.Context, id R) (RoleOf[R, P], error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	if _, ok := o.removed[id]; ok {
+		return nil, ErrRoleNotExist
+	}
+	if r, ok := o.roles[id]; ok {
+		return r, nil
+	}
+	r, err := o.base.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &overlayRole[R, P]{overlay: o, base: r}, nil
+}
+
+// RoleIDs returns the IDs of the base roles not removed and of the roles
+// added to the overlay.
+func (o *OverlayRBACOf[R, P]) RoleIDs(ctx context.Context) []R {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	return o.roleIDs(ctx)
+}
+
+// SetParents bind `parents` to the role `id` in the overlay.
+// If the role or any of parents is not existing,
+// an error will be returned.
+func (o *OverlayRBACOf[R, P]) SetParents(ctx context.Context, id R, parents ...R) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	current, err := o.checkedParents(ctx, id, parents)
+	if err != nil {
+		return err
+	}
+	current = o.override(id, current)
+	for _, parent := range parents {
+		current[parent] = empty
+	}
+	return nil
+}
+
+// GetParents return `parents` of the role `id`, from the overlay when they
+// were changed there and from the base otherwise.
+// If the role is not existing, an error will be returned.
+func (o *OverlayRBACOf[R, P]) GetParents(ctx context.Context, id R) ([]R, error) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	if !o.exists(ctx, id) {
+		return nil, ErrRoleNotExist
+	}
+	ids, err := o.parentsOf(ctx, id)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	parents := make([]R, 0, len(ids))
+	for parent := range ids {
+		parents = append(parents, parent)
+	}
+	return parents, nil
+}
+
+// RemoveParents unbind `parents` from the role `id` in the overlay.
+// If the role or any parent is not existing,
+// an error will be returned.
+func (o *OverlayRBACOf[R, P]) RemoveParents(ctx context.Context, id R, parents ...R) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	current, err := o.checkedParents(ctx, id, parents)
+	if err != nil {
+		return err
+	}
+	current = o.override(id, current)
+	for _, parent := range parents {
+		delete(current, parent)
+	}
+	return nil
+}
+
+// IsGranted tests if the role `id` has permission `p`, resolving roles and
+// parents through the overlay. When the overlay changed neither the role
+// nor any of its ancestors, the base decides.
+func (o *OverlayRBACOf[R, P]) IsGranted(ctx context.Context, id R, p Permission[P]) bool {
+	if p == nil {
+		return false
+	}
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	closure, touched := o.closure(ctx, id)
+	if !touched {
+		return o.base.IsGranted(ctx, id, p)
+	}
+	imp := ImplicationsOf[P](o.base)
+	for _, role := range closure {
+		if role.Permit(ctx, p) || permitImplied(ctx, imp, role, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check tests if the role `id` has permission `p`, like IsGranted, but
+// reports an error when `ctx` is already cancelled or past its deadline.
+func (o *OverlayRBACOf[R, P]) Check(ctx context.Context, id R, p Permission[P]) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return o.IsGranted(ctx, id, p), nil
+}
+
+// Reset drops every customization, exposing the base as is.
+func (o *OverlayRBACOf[R, P]) Reset() {
+	o.mutex.Lock()
+	o.roles = make(RolesOf[R, P])
+	o.parents = make(map[R]map[R]struct{})
+	o.removed = make(map[R]struct{})
+	o.mutex.Unlock()
+}
+
+// closure returns the role `id` and its ancestors as resolved through the
+// overlay, and whether the overlay changed any of them. The caller holds
+// the mutex.
+func (o *OverlayRBACOf[R, P]) closure(ctx context.Context, id R) ([]RoleOf[R, P], bool) {
+	var roles []RoleOf[R, P]
+	touched := false
+	seen := make(map[R]struct{})
+	stack := []R{id}
+	for len(stack) > 0 {
+		rid := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := seen[rid]; ok {
+			continue
+		}
+		seen[rid] = empty
+		if o.touched(rid) {
+			touched = true
+		}
+		role, err := o.get(ctx, rid)
+		if err != nil {
+			continue
+		}
+		roles = append(roles, role)
+		parents, err := o.parentsOf(ctx, rid)
+		if err != nil {
+			continue
+		}
+		for parent := range parents {
+			stack = append(stack, parent)
+		}
+	}
+	return roles, touched
+}
+
+// touched reports whether the overlay added, copied, removed or re-parented
+// the role `id`. The caller holds the mutex.
+func (o *OverlayRBACOf[R, P]) touched(id R) bool {
+	_, added := o.roles[id]
+	_, parented := o.parents[id]
+	_, removed := o.removed[id]
+	return added || parented || removed
+}
+
+// exists reports whether `id` is visible. The caller holds the mutex.
+func (o *OverlayRBACOf[R, P]) exists(ctx context.Context, id R) bool {
+	_, err := o.get(ctx, id)
+	return err == nil
+}
+
+// get resolves the role `id` without wrapping base roles. The caller holds
+// the mutex.
+func (o *OverlayRBACOf[R, P]) get(ctx context.Context, id R) (RoleOf[R, P], error) {
+	if _, ok := o.removed[id]; ok {
+		return nil, ErrRoleNotExist
+	}
+	if r, ok := o.roles[id]; ok {
+		return r, nil
+	}
+	return o.base.Get(ctx, id)
+}
+
+func (o *OverlayRBACOf[R, P]) roleIDs(ctx context.Context) []R {
+	var ids []R
+	for _, id := range o.base.RoleIDs(ctx) {
+		if _, ok := o.removed[id]; ok {
+			continue
+		}
+		if _, ok := o.roles[id]; ok {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	for id := range o.roles {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// parentsOf returns the parent set of `id`. A set from the base is a fresh
+// copy, so the caller may keep it with override. The caller holds the mutex.
+func (o *OverlayRBACOf[R, P]) parentsOf(ctx context.Context, id R) (map[R]struct{}, error) {
+	if parents, ok := o.parents[id]; ok {
+		return parents, nil
+	}
+	set := make(map[R]struct{})
+	if _, ok := o.roles[id]; ok {
+		// added to the overlay, unknown to the base
+		if _, err := o.base.Get(ctx, id); err != nil {
+			return set, nil
+		}
+	}
+	parents, err := o.base.GetParents(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, parent := range parents {
+		set[parent] = empty
+	}
+	return set, nil
+}
+
+// override stores `parents` as the overlay parents of `id`.
+func (o *OverlayRBACOf[R, P]) override(id R, parents map[R]struct{}) map[R]struct{} {
+	o.parents[id] = parents
+	return parents
+}
+
+func (o *OverlayRBACOf[R, P]) checkedParents(ctx context.Context, id R, parents []R) (map[R]struct{}, error) {
+	if !o.exists(ctx, id) {
+		return nil, ErrRoleNotExist
+	}
+	for _, parent := range parents {
+		if !o.exists(ctx, parent) {
+			return nil, ErrRoleNotExist
+		}
+	}
+	return o.parentsOf(ctx, id)
+}
+
+// overlayRole is a base role returned by OverlayRBACOf.Get. Reads see the
+// overlay copy once there is one; Assign and Revoke create it.
+type overlayRole[R, P comparable] struct {
+	overlay *OverlayRBACOf[R, P]
+	base    RoleOf[R, P]
+}
+
+func (r *overlayRole[R, P]) current() RoleOf[R, P] {
+	r.overlay.mutex.RLock()
+	defer r.overlay.mutex.RUnlock()
+	if _, ok := r.overlay.removed[r.base.ID()]; ok {
+		return r.base
+	}
+	if role, ok := r.overlay.roles[r.base.ID()]; ok {
+		return role
+	}
+	return r.base
+}
+
+// copy returns the overlay copy of the role, creating it if needed. The
+// copy is a StdRoleOf bound to the implication registry of the base role.
+func (r *overlayRole[R, P]) copy(ctx context.Context) (RoleOf[R, P], error) {
+	o := r.overlay
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	id := r.base.ID()
+	if _, ok := o.removed[id]; ok {
+		return nil, ErrRoleNotExist
+	}
+	if role, ok := o.roles[id]; ok {
+		return role, nil
+	}
+	role := NewRoleOf[R, P](id)
+	role.SetImplications(ImplicationsOf[P](r.base))
+	if err := role.Assign(ctx, r.base.Permissions(ctx)...); err != nil {
+		return nil, err
+	}
+	o.roles[id] = role
+	return role, nil
+}
+
+func (r *overlayRole[R, P]) ID() R {
+	return r.base.ID()
+}
+
+func (r *overlayRole[R, P]) Assign(ctx context.Context, perms ...Permission[P]) error {
+	role, err := r.copy(ctx)
+	if err != nil {
+		return err
+	}
+	return role.Assign(ctx, perms...)
+}
+
+func (r *overlayRole[R, P]) Permit(ctx context.Context, perms ...Permission[P]) bool {
+	return r.current().Permit(ctx, perms...)
+}
+
+func (r *overlayRole[R, P]) Revoke(ctx context.Context, perms ...Permission[P]) error {
+	role, err := r.copy(ctx)
+	if err != nil {
+		return err
+	}
+	return role.Revoke(ctx, perms...)
+}
+
+func (r *overlayRole[R, P]) Permissions(ctx context.Context) []Permission[P] {
+	return r.current().Permissions(ctx)
+}
+
+func (r *overlayRole[R, P]) PermissionsMap(ctx context.Context) map[P]Permission[P] {
+	return r.current().PermissionsMap(ctx)
+}
+
+func (r *overlayRole[R, P]) Get(ctx context.Context, id P) (Permission[P], bool) {
+	return r.current().Get(ctx, id)
+}
+
+func (r *overlayRole[R, P]) FilterPermissions(ctx context.Context) map[P]Permission[P] {
+	return r.current().FilterPermissions(ctx)
+}
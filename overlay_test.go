@@ -0,0 +1,153 @@
+package gorbac
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestOverlayRBAC(t *testing.T) {
+	ctx := context.Background()
+	base := New[string]()
+	rA := NewRole("role-a")
+	assert(t, rA.Assign(ctx, pA))
+	rB := NewRole("role-b")
+	assert(t, rB.Assign(ctx, pB))
+	rC := NewRole("role-c")
+	assert(t, rC.Assign(ctx, pC))
+	for _, r := range []*StdRole[string]{rA, rB, rC} {
+		assert(t, base.Add(ctx, r))
+	}
+	assert(t, base.SetParents(ctx, "role-b", "role-a"))
+	assert(t, base.SetParents(ctx, "role-c", "role-b"))
+
+	pD := NewPermission("permission-d")
+	overlay := NewOverlay[string, string](base)
+	var _ RBAC[string] = overlay
+	if !overlay.IsGranted(ctx, "role-c", pA) {
+		t.Fatal("role-c should inherit permission-a from the base")
+	}
+
+	// revoking through the overlay copies the base role
+	role, err := overlay.Get(ctx, "role-a")
+	assert(t, err)
+	assert(t, role.Revoke(ctx, pA))
+	if role.Permit(ctx, pA) || overlay.IsGranted(ctx, "role-c", pA) {
+		t.Fatal("permission-a should be revoked in the overlay")
+	}
+	if !rA.Permit(ctx, pA) || !base.IsGranted(ctx, "role-c", pA) {
+		t.Fatal("the base should keep permission-a")
+	}
+
+	// extra roles and parents
+	rD := NewRole("role-d")
+	assert(t, rD.Assign(ctx, pD))
+	assert(t, overlay.Add(ctx, rD))
+	if err := overlay.Add(ctx, NewRole("role-a")); err != ErrRoleExist {
+		t.Fatalf("%s expected, but %v got", ErrRoleExist, err)
+	}
+	assert(t, overlay.SetParents(ctx, "role-b", "role-d"))
+	parents, err := overlay.GetParents(ctx, "role-b")
+	assert(t, err)
+	slices.Sort(parents)
+	if !slices.Equal(parents, []string{"role-a", "role-d"}) {
+		t.Fatalf("unexpected parents %v", parents)
+	}
+	if !overlay.IsGranted(ctx, "role-c", pD) || base.IsGranted(ctx, "role-c", pD) {
+		t.Fatal("permission-d should only be inherited in the overlay")
+	}
+	if _, err := base.Get(ctx, "role-d"); err != ErrRoleNotExist {
+		t.Fatalf("%s expected, but %v got", ErrRoleNotExist, err)
+	}
+	assert(t, overlay.RemoveParents(ctx, "role-c", "role-b"))
+	if overlay.IsGranted(ctx, "role-c", pB) || !base.IsGranted(ctx, "role-c", pB) {
+		t.Fatal("role-c should only lose role-b in the overlay")
+	}
+
+	// removing a base role hides it and its edges
+	assert(t, overlay.Remove(ctx, "role-a"))
+	if _, err := overlay.Get(ctx, "role-a"); err != ErrRoleNotExist {
+		t.Fatalf("%s expected, but %v got", ErrRoleNotExist, err)
+	}
+	parents, err = overlay.GetParents(ctx, "role-b")
+	assert(t, err)
+	if !slices.Equal(parents, []string{"role-d"}) {
+		t.Fatalf("unexpected parents %v", parents)
+	}
+	ids := overlay.RoleIDs(ctx)
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"role-b", "role-c", "role-d"}) {
+		t.Fatalf("unexpected role IDs %v", ids)
+	}
+	if len(base.RoleIDs(ctx)) != 3 {
+		t.Fatal("the base should keep every role")
+	}
+	assert(t, overlay.Add(ctx, NewRole("role-a")))
+	parents, err = overlay.GetParents(ctx, "role-a")
+	if err != nil || parents != nil {
+		t.Fatalf("a re-added role should have no parents, got %v, %v", parents, err)
+	}
+
+	// cycles created in the overlay do not hang IsGranted
+	assert(t, overlay.SetParents(ctx, "role-d", "role-b"))
+	if overlay.IsGranted(ctx, "role-b", pC) {
+		t.Fatal("role-b should not be granted permission-c")
+	}
+
+	// removing an overlay-only role drops its parents
+	assert(t, overlay.Remove(ctx, "role-d"))
+	if _, err := overlay.GetParents(ctx, "role-d"); err != ErrRoleNotExist {
+		t.Fatalf("%s expected, but %v got", ErrRoleNotExist, err)
+	}
+	if _, ok := overlay.parents["role-d"]; ok {
+		t.Fatal("the parents of a removed role should be dropped")
+	}
+	assert(t, overlay.Add(ctx, rD))
+	parents, err = overlay.GetParents(ctx, "role-d")
+	if err != nil || len(parents) != 0 {
+		t.Fatalf("a re-added role should have no parents, got %v, %v", parents, err)
+	}
+
+	overlay.Reset()
+	if !overlay.IsGranted(ctx, "role-c", pA) || len(overlay.RoleIDs(ctx)) != 3 {
+		t.Fatal("Reset should expose the base")
+	}
+}
+
+func TestOverlayRBACImplications(t *testing.T) {
+	ctx := context.Background()
+	read, write, del := NewPermission("read"), NewPermission("write"), NewPermission("delete")
+	base := New[string]()
+	rbacImp := NewImplications[string]()
+	assert(t, rbacImp.SetImplies(ctx, "write", "read"))
+	base.SetImplications(rbacImp)
+	roleImp := NewImplications[string]()
+	assert(t, roleImp.SetImplies(ctx, "admin", "delete"))
+	editor := NewRole("editor")
+	editor.SetImplications(roleImp)
+	assert(t, editor.Assign(ctx, write, NewPermission("admin")))
+	assert(t, base.Add(ctx, editor))
+	assert(t, base.Add(ctx, NewRole("child")))
+	assert(t, base.SetParents(ctx, "child", "editor"))
+
+	overlay := NewOverlay[string, string](base)
+	for _, id := range []string{"editor", "child"} {
+		if !overlay.IsGranted(ctx, id, read) || !overlay.IsGranted(ctx, id, del) {
+			t.Fatalf("%s: an unchanged overlay should decide like its base", id)
+		}
+	}
+
+	// a copied role keeps its implications, the base RBAC ones still apply
+	role, err := overlay.Get(ctx, "editor")
+	assert(t, err)
+	assert(t, role.Revoke(ctx, NewPermission("unknown")))
+	for _, id := range []string{"editor", "child"} {
+		if !overlay.IsGranted(ctx, id, read) || !overlay.IsGranted(ctx, id, del) {
+			t.Fatalf("%s: implications should survive the copy", id)
+		}
+	}
+	assert(t, role.Revoke(ctx, write))
+	if overlay.IsGranted(ctx, "child", read) || !base.IsGranted(ctx, "child", read) {
+		t.Fatal("read should only be lost in the overlay")
+	}
+}
@@ -0,0 +1,183 @@
+package gorbac
+
+import (
+	"context"
+	"sync"
+)
+
+// Implications is a registry of permission implication edges.
+//
+// An edge `stronger -> weaker` means that holding the stronger permission
+// grants the weaker one as well, e.g. `admin -> write -> read`. Edges are
+// transitive. A registry can be shared by `StdRBACOf` and `StdRoleOf` instances.
+// T is the type of permission ID.
+type Implications[T comparable] struct {
+	mutex sync.RWMutex
+	// weaker permissions keyed by the stronger one
+	implies map[T]map[T]struct{}
+	// stronger permissions keyed by the weaker one
+	impliedBy map[T]map[T]struct{}
+}
+
+// NewImplications returns an empty implication registry.
+func NewImplications[T comparable]() *Implications[T] {
+	return &Implications[T]{
+		implies:   make(map[T]map[T]struct{}),
+		impliedBy: make(map[T]map[T]struct{}),
+	}
+}
+
+// SetImplies binds `weaker` permissions to the permission `stronger`.
+func (imp *Implications[T]) SetImplies(_ context.Context, stronger T, weaker ...T) error {
+	imp.mutex.Lock()
+	defer imp.mutex.Unlock()
+	for _, w := range weaker {
+		if _, ok := imp.implies[stronger]; !ok {
+			imp.implies[stronger] = make(map[T]struct{})
+		}
+		imp.implies[stronger][w] = empty
+		if _, ok := imp.impliedBy[w]; !ok {
+			imp.impliedBy[w] = make(map[T]struct{})
+		}
+		imp.impliedBy[w][stronger] = empty
+	}
+	return nil
+}
+
+// GetImplies returns the permissions directly implied by `stronger`.
+// A nil slice will be returned if it doesn't imply anything.
+func (imp *Implications[T]) GetImplies(_ context.Context, stronger T) []T {
+	imp.mutex.RLock()
+	defer imp.mutex.RUnlock()
+	var weaker []T
+	for w := range imp.implies[stronger] {
+		weaker = append(weaker, w)
+	}
+	return weaker
+}
+
+// RemoveImplies unbinds `weaker` permissions from the permission `stronger`.
+func (imp *Implications[T]) RemoveImplies(_ context.Context, stronger T, weaker ...T) error {
+	imp.mutex.Lock()
+	defer imp.mutex.Unlock()
+	for _, w := range weaker {
+		delete(imp.implies[stronger], w)
+		delete(imp.impliedBy[w], stronger)
+	}
+	return nil
+}
+
+// PermissionIDs returns all permission IDs taking part in any implication.
+func (imp *Implications[T]) PermissionIDs(_ context.Context) []T {
+	imp.mutex.RLock()
+	defer imp.mutex.RUnlock()
+	seen := make(map[T]struct{}, len(imp.implies)+len(imp.impliedBy))
+	ids := make([]T, 0, len(imp.implies)+len(imp.impliedBy))
+	for _, m := range []map[T]map[T]struct{}{imp.implies, imp.impliedBy} {
+		for id := range m {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = empty
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Implies tests if holding `stronger` grants `weaker`, directly or transitively.
+// Every permission implies itself.
+func (imp *Implications[T]) Implies(ctx context.Context, stronger, weaker T) bool {
+	if stronger == weaker {
+		return true
+	}
+	for _, id := range imp.Stronger(ctx, weaker) {
+		if id == stronger {
+			return true
+		}
+	}
+	return false
+}
+
+// Stronger returns every permission ID which implies `weaker`, directly or
+// transitively. `weaker` itself is not included.
+func (imp *Implications[T]) Stronger(_ context.Context, weaker T) []T {
+	imp.mutex.RLock()
+	defer imp.mutex.RUnlock()
+	if len(imp.impliedBy[weaker]) == 0 {
+		return nil
+	}
+	seen := map[T]struct{}{weaker: empty}
+	var result []T
+	queue := []T{weaker}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for s := range imp.impliedBy[id] {
+			if _, ok := seen[s]; ok {
+				continue
+			}
+			seen[s] = empty
+			result = append(result, s)
+			queue = append(queue, s)
+		}
+	}
+	return result
+}
+
+// ImplyCircle returns an error when detecting any circle implication.
+func ImplyCircle[T comparable](ctx context.Context, imp *Implications[T]) error {
+	imp.mutex.RLock()
+	defer imp.mutex.RUnlock()
+	skipped := make(map[T]struct{})
+	var stack []T
+	var visit func(T) error
+	visit = func(id T) error {
+		if _, ok := skipped[id]; ok {
+			return nil
+		}
+		for _, item := range stack {
+			if item == id {
+				return ErrFoundCircle
+			}
+		}
+		stack = append(stack, id)
+		for w := range imp.implies[id] {
+			if err := visit(w); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		skipped[id] = empty
+		return nil
+	}
+	for id := range imp.implies {
+		if err := visit(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// permitImplied tests if the role holds any permission which implies `p`.
+func permitImplied[R, P comparable](ctx context.Context, imp *Implications[P], role RoleOf[R, P], p Permission[P]) bool {
+	if imp == nil || p == nil {
+		return false
+	}
+	for _, id := range imp.Stronger(ctx, p.ID()) {
+		if _, ok := role.Get(ctx, id); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ImplicationsOf returns the implication registry bound to `v`, an RBAC or
+// a role with an `Implications() *Implications[P]` method such as StdRBACOf
+// and StdRoleOf, or nil.
+func ImplicationsOf[P comparable](v any) *Implications[P] {
+	if i, ok := v.(interface{ Implications() *Implications[P] }); ok {
+		return i.Implications()
+	}
+	return nil
+}
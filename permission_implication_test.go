@@ -0,0 +1,80 @@
+package gorbac
+
+import (
+	"context"
+	"testing"
+)
+
+func TestImplications(t *testing.T) {
+	ctx := context.Background()
+	imp := NewImplications[string]()
+	assert(t, imp.SetImplies(ctx, "admin", "write"))
+	assert(t, imp.SetImplies(ctx, "write", "read"))
+	if !imp.Implies(ctx, "admin", "read") {
+		t.Fatal("[admin] should imply [read] transitively")
+	}
+	if imp.Implies(ctx, "read", "admin") {
+		t.Fatal("[read] should not imply [admin]")
+	}
+	if len(imp.GetImplies(ctx, "admin")) != 1 {
+		t.Fatal("[admin] should directly imply one permission")
+	}
+	if err := ImplyCircle(ctx, imp); err != nil {
+		t.Fatal(err)
+	}
+	assert(t, imp.SetImplies(ctx, "read", "admin"))
+	if err := ImplyCircle(ctx, imp); err != ErrFoundCircle {
+		t.Fatalf("%s needed", ErrFoundCircle)
+	}
+	if len(imp.Stronger(ctx, "read")) != 2 {
+		t.Fatal("Stronger should terminate on circles")
+	}
+	assert(t, imp.RemoveImplies(ctx, "read", "admin"))
+	if err := ImplyCircle(ctx, imp); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImplicationsGranted(t *testing.T) {
+	ctx := context.Background()
+	imp := NewImplications[string]()
+	assert(t, imp.SetImplies(ctx, "admin", "write"))
+	assert(t, imp.SetImplies(ctx, "write", "read"))
+
+	admin := NewRole("admin")
+	assert(t, admin.Assign(ctx, NewPermission("admin")))
+	if admin.Permit(ctx, NewPermission("read")) {
+		t.Fatal("[read] should not permit without implications")
+	}
+	admin.SetImplications(imp)
+	if admin.Implications() != imp {
+		t.Fatal("the bound registry should be returned")
+	}
+	if !admin.Permit(ctx, NewPermission("read"), NewPermission("write")) {
+		t.Fatal("[read] and [write] should permit through [admin]")
+	}
+
+	editor := NewRole("editor")
+	assert(t, editor.Assign(ctx, NewPermission("write")))
+	child := NewRole("child")
+	rbac := New[string]()
+	assert(t, rbac.Add(ctx, editor))
+	assert(t, rbac.Add(ctx, child))
+	assert(t, rbac.SetParents(ctx, "child", "editor"))
+	if rbac.IsGranted(ctx, "child", NewPermission("read")) {
+		t.Fatal("[read] should not be granted without implications")
+	}
+	rbac.SetImplications(imp)
+	if rbac.Implications() != imp {
+		t.Fatal("the bound registry should be returned")
+	}
+	if !rbac.IsGranted(ctx, "child", NewPermission("read")) {
+		t.Fatal("[read] should be granted through the inherited [write]")
+	}
+	if rbac.IsGranted(ctx, "child", NewPermission("admin")) {
+		t.Fatal("[admin] should not be granted through [write]")
+	}
+	if rbac.IsGranted(ctx, "child", permissionZero) {
+		t.Fatal("child should not have nil permission")
+	}
+}
@@ -0,0 +1,147 @@
+package gorbac
+
+import (
+	"strings"
+)
+
+// MatcherIndex accelerates `StdRoleOf.Permit` for one kind of permission.
+//
+// Match must report whether any indexed permission `rp` satisfies
+// `rp.Match(p)`, so indexed and scanned permissions behave the same.
+type MatcherIndex[P comparable] interface {
+	Insert(Permission[P])
+	Delete(Permission[P])
+	Match(Permission[P]) bool
+}
+
+// IndexablePermission is implemented by custom permission types which
+// provide a MatcherIndex. Permissions returning the same IndexKey share one
+// index; an empty IndexKey opts out of indexing.
+//
+// StdPermission and LayerPermission are indexed by their exact type only, so
+// types embedding them are scanned and their own Match is honored.
+type IndexablePermission[P comparable] interface {
+	Permission[P]
+	IndexKey() string
+	NewMatcherIndex() MatcherIndex[P]
+}
+
+// indexOf returns the index key and constructor of `p`, or false when `p` is
+// scanned.
+func indexOf[P comparable](p Permission[P]) (string, func() MatcherIndex[P], bool) {
+	switch bp := any(p).(type) {
+	case StdPermission[P]:
+		return "gorbac:std", func() MatcherIndex[P] { return make(hashIndex[P]) }, true
+	case LayerPermission:
+		return "gorbac:layer:" + bp.Sep, func() MatcherIndex[P] {
+			return any(newLayerTrie()).(MatcherIndex[P])
+		}, true
+	}
+	ip, ok := p.(IndexablePermission[P])
+	if !ok || ip.IndexKey() == "" {
+		return "", nil, false
+	}
+	return ip.IndexKey(), ip.NewMatcherIndex, true
+}
+
+// hashIndex indexes StdPermission by ID.
+type hashIndex[T comparable] map[T]struct{}
+
+func (idx hashIndex[T]) Insert(p Permission[T]) {
+	idx[p.ID()] = empty
+}
+
+func (idx hashIndex[T]) Delete(p Permission[T]) {
+	delete(idx, p.ID())
+}
+
+func (idx hashIndex[T]) Match(p Permission[T]) bool {
+	_, ok := idx[p.ID()]
+	return ok
+}
+
+// newLayerTrie returns a prefix trie over permission layers.
+func newLayerTrie() *layerTrie {
+	return &layerTrie{
+		ids:  make(map[string]struct{}),
+		root: &layerNode{},
+	}
+}
+
+type layerNode struct {
+	children map[string]*layerNode
+	terminal bool
+}
+
+// layerTrie indexes LayerPermission sharing one separator.
+type layerTrie struct {
+	ids  map[string]struct{}
+	root *layerNode
+}
+
+func (idx *layerTrie) Insert(p Permission[string]) {
+	lp, ok := p.(LayerPermission)
+	if !ok {
+		return
+	}
+	idx.ids[lp.SID] = empty
+	node := idx.root
+	for _, layer := range strings.Split(lp.SID, lp.Sep) {
+		if node.children == nil {
+			node.children = make(map[string]*layerNode)
+		}
+		next, ok := node.children[layer]
+		if !ok {
+			next = &layerNode{}
+			node.children[layer] = next
+		}
+		node = next
+	}
+	node.terminal = true
+}
+
+func (idx *layerTrie) Delete(p Permission[string]) {
+	lp, ok := p.(LayerPermission)
+	if !ok {
+		return
+	}
+	delete(idx.ids, lp.SID)
+	layers := strings.Split(lp.SID, lp.Sep)
+	path := make([]*layerNode, 0, len(layers)+1)
+	node := idx.root
+	path = append(path, node)
+	for _, layer := range layers {
+		if node = node.children[layer]; node == nil {
+			return
+		}
+		path = append(path, node)
+	}
+	node.terminal = false
+	// prune the branches which no longer end any permission
+	for i := len(layers); i > 0; i-- {
+		if path[i].terminal || len(path[i].children) > 0 {
+			break
+		}
+		delete(path[i-1].children, layers[i-1])
+	}
+}
+
+func (idx *layerTrie) Match(p Permission[string]) bool {
+	if _, ok := idx.ids[p.ID()]; ok {
+		return true
+	}
+	q, ok := p.(LayerPermission)
+	if !ok {
+		return false
+	}
+	node := idx.root
+	for _, layer := range strings.Split(q.SID, q.Sep) {
+		if node = node.children[layer]; node == nil {
+			return false
+		}
+		if node.terminal {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package gorbac
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+type prefixPermission struct {
+	StdPermission[string]
+}
+
+func (p prefixPermission) Match(a Permission[string]) bool {
+	return len(a.ID()) >= len(p.SID) && a.ID()[:len(p.SID)] == p.SID
+}
+
+type embeddedLayer struct {
+	LayerPermission
+}
+
+func TestLayerTrieIndex(t *testing.T) {
+	held := []LayerPermission{
+		NewLayerPermission("admin", "::"),
+		NewLayerPermission("profile::read", "::"),
+		NewLayerPermission("a/b", "/"),
+	}
+	queries := []Permission[string]{
+		NewLayerPermission("admin", "::"),
+		NewLayerPermission("admin::dashboard", "::"),
+		NewLayerPermission("profile", "::"),
+		NewLayerPermission("profile::read::own", "::"),
+		NewLayerPermission("profile::write", "::"),
+		NewLayerPermission("a/b/c", "/"),
+		NewLayerPermission("a::b", "::"),
+		NewPermission("admin"),
+		NewPermission("admin::dashboard"),
+	}
+	idx := newLayerTrie()
+	for _, p := range held {
+		idx.Insert(p)
+	}
+	for _, q := range queries {
+		expected := false
+		for _, p := range held {
+			if p.Match(q) {
+				expected = true
+			}
+		}
+		if got := idx.Match(q); got != expected {
+			t.Fatalf("`%s`: index returned %v, scanning returned %v", q.ID(), got, expected)
+		}
+	}
+	idx.Delete(held[1])
+	if idx.Match(NewLayerPermission("profile::read::own", "::")) {
+		t.Fatal("`profile::read` should be removed from the index")
+	}
+	if !idx.Match(NewLayerPermission("admin::password", "::")) {
+		t.Fatal("`admin` should stay in the index")
+	}
+}
+
+func TestStdRoleIndexedPermit(t *testing.T) {
+	ctx := context.Background()
+	r := NewRole("role")
+	assert(t, r.Assign(ctx,
+		NewLayerPermission("admin", "::"),
+		NewPermission("read"),
+		prefixPermission{StdPermission[string]{"report-"}},
+		embeddedLayer{NewLayerPermission("orders", "::")},
+	))
+	if !r.Permit(ctx, NewLayerPermission("admin::dashboard", "::")) {
+		t.Fatal("`admin` should permit `admin::dashboard`")
+	}
+	if !r.Permit(ctx, NewPermission("report-2024")) {
+		t.Fatal("the Match of a type embedding StdPermission should be honored")
+	}
+	if !r.Permit(ctx, NewLayerPermission("orders::list", "::")) {
+		t.Fatal("a type embedding LayerPermission should still match")
+	}
+	if r.Permit(ctx, NewPermission("write")) {
+		t.Fatal("`write` should not permit")
+	}
+	// replacing a permission with the same ID moves it between indexes
+	assert(t, r.Assign(ctx, NewPermission("admin")))
+	if r.Permit(ctx, NewLayerPermission("admin::dashboard", "::")) {
+		t.Fatal("`admin` is no longer layered")
+	}
+	assert(t, r.Revoke(ctx, NewLayerPermission("read", "::")))
+	if r.Permit(ctx, NewPermission("read")) {
+		t.Fatal("`read` should be revoked")
+	}
+}
+
+func BenchmarkStdRolePermitLayerMiss(b *testing.B) {
+	ctx := context.Background()
+	r := NewRole("role")
+	for i := 0; i < 500; i++ {
+		if err := r.Assign(ctx, NewLayerPermission(fmt.Sprintf("module%d::read", i), "::")); err != nil {
+			b.Fatal(err)
+		}
+	}
+	p := NewLayerPermission("module-x::read", "::")
+	for i := 0; i < b.N; i++ {
+		r.Permit(ctx, p)
+	}
+}
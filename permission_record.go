@@ -0,0 +1,67 @@
+package gorbac
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupportedPermission occurred if a permission type has no PermissionRecord form
+var ErrUnsupportedPermission = errors.New("Permission type is not supported")
+
+// PermissionRecord is the serialisable form of the built-in permission types.
+//
+//   - StdPermission: only ID is set.
+//   - LayerPermission: Sep is set (string IDs only).
+//   - FilterPermission: Filter is set.
+//
+// A bare JSON value, e.g. `"read"`, decodes as a StdPermission record.
+type PermissionRecord[T comparable] struct {
+	ID     T      `json:"id"`
+	Sep    string `json:"sep,omitempty"`
+	Filter string `json:"filter,omitempty"`
+}
+
+// RecordOf returns the record describing `p`.
+// ErrUnsupportedPermission is returned for custom permission types.
+func RecordOf[T comparable](p Permission[T]) (PermissionRecord[T], error) {
+	switch v := p.(type) {
+	case StdPermission[T]:
+		return PermissionRecord[T]{ID: v.SID}, nil
+	case FilterPermission[T]:
+		return PermissionRecord[T]{ID: v.SID, Filter: v.Filter}, nil
+	}
+	if v, ok := any(p).(LayerPermission); ok {
+		return PermissionRecord[T]{ID: p.ID(), Sep: v.Sep}, nil
+	}
+	return PermissionRecord[T]{}, fmt.Errorf("%w: %T", ErrUnsupportedPermission, p)
+}
+
+// Permission builds the permission described by the record.
+func (r PermissionRecord[T]) Permission() (Permission[T], error) {
+	switch {
+	case r.Sep != "" && r.Filter != "":
+		return nil, fmt.Errorf("permission %v: sep and filter are exclusive", r.ID)
+	case r.Sep != "":
+		id, ok := any(r.ID).(string)
+		if !ok {
+			return nil, fmt.Errorf("permission %v: layered permissions need string IDs", r.ID)
+		}
+		return any(NewLayerPermission(id, r.Sep)).(Permission[T]), nil
+	case r.Filter != "":
+		return NewFilterPermission(r.ID, r.Filter), nil
+	default:
+		return NewPermission(r.ID), nil
+	}
+}
+
+// UnmarshalJSON accepts both the object form and a bare ID.
+func (r *PermissionRecord[T]) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		type plain PermissionRecord[T]
+		return json.Unmarshal(data, (*plain)(r))
+	}
+	*r = PermissionRecord[T]{}
+	return json.Unmarshal(data, &r.ID)
+}
@@ -0,0 +1,40 @@
+package gorbac
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPermissionRecord(t *testing.T) {
+	perms := []Permission[string]{
+		NewPermission("read"),
+		NewLayerPermission("admin::dashboard", "::"),
+		NewFilterPermission("project:read", "creator_id == 1"),
+	}
+	for _, p := range perms {
+		record, err := RecordOf(p)
+		assert(t, err)
+		text, err := json.Marshal(record)
+		assert(t, err)
+		var decoded PermissionRecord[string]
+		assert(t, json.Unmarshal(text, &decoded))
+		got, err := decoded.Permission()
+		assert(t, err)
+		if got != p {
+			t.Fatalf("%#v expected, but %#v got", p, got)
+		}
+	}
+
+	var bare PermissionRecord[int]
+	assert(t, json.Unmarshal([]byte(`42`), &bare))
+	if p, err := bare.Permission(); err != nil || p != NewPermission(42) {
+		t.Fatalf("bare ID should decode as a StdPermission: %v", err)
+	}
+	if _, err := (PermissionRecord[int]{ID: 1, Sep: "::"}).Permission(); err == nil {
+		t.Fatal("layered permissions need string IDs")
+	}
+	if _, err := RecordOf[string](prefixPermission{}); !errors.Is(err, ErrUnsupportedPermission) {
+		t.Fatalf("%s needed", ErrUnsupportedPermission)
+	}
+}
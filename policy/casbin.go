@@ -0,0 +1,313 @@
+package policy
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+// ErrCasbinUnsupported is the error of the issues reporting Casbin
+// constructs without a gorbac equivalent.
+var ErrCasbinUnsupported = errors.New("Casbin construct is not supported")
+
+const (
+	// DefaultCasbinDomainSeparator joins a domain and a role into a role ID.
+	DefaultCasbinDomainSeparator = "/"
+	// DefaultCasbinActionSeparator joins an object and an action into a
+	// permission ID.
+	DefaultCasbinActionSeparator = ":"
+)
+
+type casbinConfig struct {
+	domains   bool
+	domainSep string
+	actionSep string
+}
+
+// CasbinOption customizes ImportCasbin and ExportCasbin.
+type CasbinOption func(*casbinConfig)
+
+// WithCasbinDomains reads and writes the rules of a model with domains,
+// "p, role, domain, permission" and "g, role, parent, domain". gorbac has no
+// domains, so roles are qualified as domain + `sep` + role. An empty `sep`
+// means DefaultCasbinDomainSeparator.
+func WithCasbinDomains(sep string) CasbinOption {
+	return func(cfg *casbinConfig) {
+		cfg.domains = true
+		if sep != "" {
+			cfg.domainSep = sep
+		}
+	}
+}
+
+// WithCasbinActionSeparator sets how the object and the action of
+// "p, role, object, action" rules join into a permission ID. An empty `sep`
+// keeps permissions in a single field and rejects object and action rules.
+func WithCasbinActionSeparator(sep string) CasbinOption {
+	return func(cfg *casbinConfig) {
+		cfg.actionSep = sep
+	}
+}
+
+func newCasbinConfig(opts []CasbinOption) casbinConfig {
+	cfg := casbinConfig{domainSep: DefaultCasbinDomainSeparator, actionSep: DefaultCasbinActionSeparator}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// ImportCasbin reads a Casbin policy in CSV form: "p, role, permission" (or
+// "p, role, object, action") rules assign standard permissions and
+// "g, role, parent" rules set parents. Roles only named by g rules are
+// declared without permissions.
+//
+// Rules without a gorbac equivalent are skipped and reported as issues
+// wrapping ErrCasbinUnsupported: other policy types such as g2, extra fields
+// such as effects, and wildcards, whose meaning depends on the matcher of
+// the model. The error is for unreadable input.
+func ImportCasbin(r io.Reader, opts ...CasbinOption) (*Document, []Issue, error) {
+	cfg := newCasbinConfig(opts)
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+	doc := &Document{
+		Roles:   make(map[string][]gorbac.PermissionRecord[string]),
+		Parents: make(map[string][]string),
+	}
+	var issues []Issue
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		if len(fields) == 1 && fields[0] == "" {
+			continue
+		}
+		var issue string
+		switch fields[0] {
+		case "p":
+			issue = cfg.importPermission(doc, fields[1:])
+		case "g":
+			issue = cfg.importParent(doc, fields[1:])
+		default:
+			issue = fmt.Sprintf("%s rules are not supported", fields[0])
+		}
+		if issue != "" {
+			issues = append(issues, Issue{Message: fmt.Sprintf("line %d: %s", line, issue), Err: ErrCasbinUnsupported})
+		}
+	}
+	return doc, issues, nil
+}
+
+// checkFields reports empty fields and wildcards.
+func checkFields(fields []string) string {
+	for _, field := range fields {
+		if field == "" {
+			return "empty field"
+		}
+		if strings.Contains(field, "*") {
+			return fmt.Sprintf("wildcard %q depends on the matcher", field)
+		}
+	}
+	return ""
+}
+
+func (cfg casbinConfig) qualify(domain, role string) string {
+	return domain + cfg.domainSep + role
+}
+
+func (cfg casbinConfig) importPermission(doc *Document, args []string) string {
+	if issue := checkFields(args); issue != "" {
+		return issue
+	}
+	n := 2
+	if cfg.domains {
+		n = 3
+	}
+	if len(args) < n {
+		return "p rule without a permission"
+	}
+	role, rest := args[0], args[n-1:]
+	if cfg.domains {
+		role = cfg.qualify(args[1], role)
+	}
+	var id string
+	switch {
+	case len(rest) == 1:
+		id = rest[0]
+	case len(rest) == 2 && cfg.actionSep != "":
+		id = rest[0] + cfg.actionSep + rest[1]
+	default:
+		return fmt.Sprintf("p rule with %d fields is not supported", len(args))
+	}
+	record := gorbac.PermissionRecord[string]{ID: id}
+	if !slices.Contains(doc.Roles[role], record) {
+		doc.Roles[role] = append(doc.Roles[role], record)
+	}
+	return ""
+}
+
+func (cfg casbinConfig) importParent(doc *Document, args []string) string {
+	if issue := checkFields(args); issue != "" {
+		return issue
+	}
+	switch {
+	case len(args) == 3 && !cfg.domains:
+		return "g rule with a domain, see WithCasbinDomains"
+	case len(args) == 2 && cfg.domains:
+		return "g rule without a domain"
+	case len(args) < 2 || len(args) > 3:
+		return fmt.Sprintf("g rule with %d fields is not supported", len(args))
+	}
+	role, parent := args[0], args[1]
+	if cfg.domains {
+		role, parent = cfg.qualify(args[2], role), cfg.qualify(args[2], parent)
+	}
+	for _, id := range []string{role, parent} {
+		if _, ok := doc.Roles[id]; !ok {
+			doc.Roles[id] = []gorbac.PermissionRecord[string]{}
+		}
+	}
+	if !slices.Contains(doc.Parents[role], parent) {
+		doc.Parents[role] = append(doc.Parents[role], parent)
+	}
+	return ""
+}
+
+// ExportCasbin writes `doc` as a Casbin policy in CSV form, see
+// ImportCasbin. Whatever Casbin cannot express is left out and reported as
+// issues wrapping ErrCasbinUnsupported: layered and filter permissions,
+// roles without permissions or inheritance and, with WithCasbinDomains,
+// roles without a domain or inheriting across domains.
+func ExportCasbin(w io.Writer, doc *Document, opts ...CasbinOption) ([]Issue, error) {
+	cfg := newCasbinConfig(opts)
+	var issues []Issue
+	unsupported := func(role, format string, args ...any) {
+		issues = append(issues, Issue{Role: role, Message: fmt.Sprintf(format, args...), Err: ErrCasbinUnsupported})
+	}
+	// split returns the domain and the name of a role
+	split := func(id string) (string, string, bool) {
+		if !cfg.domains {
+			return "", id, true
+		}
+		domain, name, ok := strings.Cut(id, cfg.domainSep)
+		return domain, name, ok && domain != "" && name != ""
+	}
+
+	var policies, groupings [][]string
+	expressed := make(map[string]bool)
+	for _, id := range doc.RoleIDs() {
+		domain, name, ok := split(id)
+		if !ok {
+			unsupported(id, "role is not qualified with a domain")
+			continue
+		}
+		for _, record := range doc.Roles[id] {
+			switch {
+			case record.Filter != "":
+				unsupported(id, "filter permission %s is not supported", record.ID)
+				continue
+			case record.Sep != "":
+				unsupported(id, "layered permission %s is not supported", record.ID)
+				continue
+			}
+			fields := []string{"p", name}
+			if cfg.domains {
+				fields = append(fields, domain)
+			}
+			fields = append(fields, cfg.splitAction(record.ID)...)
+			policies = append(policies, fields)
+			expressed[id] = true
+		}
+	}
+	children := make([]string, 0, len(doc.Parents))
+	for id := range doc.Parents {
+		children = append(children, id)
+	}
+	slices.Sort(children)
+	for _, id := range children {
+		domain, name, ok := split(id)
+		if !ok {
+			if _, declared := doc.Roles[id]; !declared {
+				unsupported(id, "role is not qualified with a domain")
+			}
+			continue
+		}
+		for _, parent := range doc.Parents[id] {
+			parentDomain, parentName, ok := split(parent)
+			if !ok || parentDomain != domain {
+				unsupported(id, "parent %q is not in domain %q", parent, domain)
+				continue
+			}
+			fields := []string{"g", name, parentName}
+			if cfg.domains {
+				fields = append(fields, domain)
+			}
+			groupings = append(groupings, fields)
+			expressed[id], expressed[parent] = true, true
+		}
+	}
+	for _, id := range doc.RoleIDs() {
+		if _, _, ok := split(id); ok && !expressed[id] {
+			unsupported(id, "role without permissions or inheritance cannot be expressed")
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	writeRules := func(rules [][]string) {
+		for _, fields := range rules {
+			for i, field := range fields {
+				if i > 0 {
+					bw.WriteString(", ")
+				}
+				bw.WriteString(quoteCasbin(field))
+			}
+			bw.WriteString("\n")
+		}
+	}
+	writeRules(policies)
+	if len(policies) > 0 && len(groupings) > 0 {
+		bw.WriteString("\n")
+	}
+	writeRules(groupings)
+	return issues, bw.Flush()
+}
+
+// splitAction splits a permission ID into an object and an action at the
+// last action separator.
+func (cfg casbinConfig) splitAction(id string) []string {
+	if cfg.actionSep == "" {
+		return []string{id}
+	}
+	i := strings.LastIndex(id, cfg.actionSep)
+	if i <= 0 || i+len(cfg.actionSep) == len(id) {
+		return []string{id}
+	}
+	return []string{id[:i], id[i+len(cfg.actionSep):]}
+}
+
+// quoteCasbin quotes a field that would not read back as is.
+func quoteCasbin(field string) string {
+	if !strings.ContainsAny(field, ",\"\r\n") && field == strings.TrimSpace(field) {
+		return field
+	}
+	return `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+}
@@ -0,0 +1,206 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+const casbinPolicy = `# migrated from casbin
+p, viewer, read
+p, editor, posts, edit
+p, editor, posts, edit
+p, "quoted, role", read
+
+g, alice, editor
+g, editor, viewer
+g2, /data/1, data-group
+p, editor, posts, delete, deny
+p, admin, *
+g, bob, editor, tenant-1
+`
+
+func TestImportCasbin(t *testing.T) {
+	ctx := context.Background()
+	doc, issues, err := ImportCasbin(strings.NewReader(casbinPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]gorbac.PermissionRecord[string]{
+		"viewer":       {{ID: "read"}},
+		"editor":       {{ID: "posts:edit"}},
+		"quoted, role": {{ID: "read"}},
+		"alice":        {},
+	}
+	if len(doc.Roles) != len(expected) {
+		t.Fatalf("unexpected roles %v", doc.RoleIDs())
+	}
+	for id, records := range expected {
+		if got := doc.Roles[id]; len(got) != len(records) || (len(got) > 0 && got[0] != records[0]) {
+			t.Fatalf("%s: %+v expected, but %+v got", id, records, got)
+		}
+	}
+	messages := []string{
+		"line 9: g2 rules are not supported",
+		"line 10: p rule with 4 fields is not supported",
+		`line 11: wildcard "*" depends on the matcher`,
+		"line 12: g rule with a domain, see WithCasbinDomains",
+	}
+	if len(issues) != len(messages) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	for i, issue := range issues {
+		if issue.Message != messages[i] || !errors.Is(issue, ErrCasbinUnsupported) {
+			t.Fatalf("%q expected, but %v got", messages[i], issue)
+		}
+	}
+
+	rbac, err := doc.Build(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rbac.IsGranted(ctx, "alice", gorbac.NewPermission("read")) || !rbac.IsGranted(ctx, "alice", gorbac.NewPermission("posts:edit")) {
+		t.Fatal("alice should inherit the permissions of editor and viewer")
+	}
+
+	if _, _, err := ImportCasbin(strings.NewReader(`p, "unterminated`)); err == nil {
+		t.Fatal("malformed CSV should fail")
+	}
+}
+
+func TestExportCasbin(t *testing.T) {
+	ctx := context.Background()
+	doc, _, err := ImportCasbin(strings.NewReader(casbinPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rbac, err := doc.Build(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err = FromRBAC(ctx, rbac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	issues, err := ExportCasbin(&b, doc)
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("unexpected issues %v, %v", issues, err)
+	}
+	expected := `p, editor, posts, edit
+p, "quoted, role", read
+p, viewer, read
+
+g, alice, editor
+g, editor, viewer
+`
+	if b.String() != expected {
+		t.Fatalf("unexpected policy:\n%s", b.String())
+	}
+	again, issues, err := ImportCasbin(strings.NewReader(b.String()))
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("unexpected issues %v, %v", issues, err)
+	}
+	if changes := Diff(doc, again); len(changes) != 0 {
+		t.Fatalf("the exported policy should import back, got %v", changes)
+	}
+
+	unsupported := &Document{Roles: map[string][]gorbac.PermissionRecord[string]{
+		"a": {{ID: "posts", Sep: ":"}, {ID: "orders", Filter: "owner_id == uid"}, {ID: "read"}},
+		"b": {},
+	}}
+	b.Reset()
+	issues, err = ExportCasbin(&b, unsupported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := []string{
+		"a: layered permission posts is not supported",
+		"a: filter permission orders is not supported",
+		"b: role without permissions or inheritance cannot be expressed",
+	}
+	if len(issues) != len(messages) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	for i, issue := range issues {
+		if issue.Error() != messages[i] || !errors.Is(issue, ErrCasbinUnsupported) {
+			t.Fatalf("%q expected, but %v got", messages[i], issue)
+		}
+	}
+	if b.String() != "p, a, read\n" {
+		t.Fatalf("unexpected policy %q", b.String())
+	}
+}
+
+func TestCasbinDomains(t *testing.T) {
+	ctx := context.Background()
+	src := `p, admin, tenant-1, data, write
+p, reader, tenant-1, data:read
+p, admin, tenant-2, data, write
+g, alice, admin, tenant-1
+g, admin, reader, tenant-1
+g, bob, admin
+`
+	doc, issues, err := ImportCasbin(strings.NewReader(src), WithCasbinDomains(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Message != "line 6: g rule without a domain" {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	rbac, err := doc.Build(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rbac.IsGranted(ctx, "tenant-1/alice", gorbac.NewPermission("data:read")) {
+		t.Fatal("alice should read data in tenant-1")
+	}
+	if rbac.IsGranted(ctx, "tenant-2/admin", gorbac.NewPermission("data:read")) {
+		t.Fatal("admin of tenant-2 should not read data")
+	}
+
+	var b strings.Builder
+	issues, err = ExportCasbin(&b, doc, WithCasbinDomains(""))
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("unexpected issues %v, %v", issues, err)
+	}
+	expected := `p, admin, tenant-1, data, write
+p, reader, tenant-1, data, read
+p, admin, tenant-2, data, write
+
+g, admin, reader, tenant-1
+g, alice, admin, tenant-1
+`
+	if b.String() != expected {
+		t.Fatalf("unexpected policy:\n%s", b.String())
+	}
+
+	doc.Roles["global"] = []gorbac.PermissionRecord[string]{{ID: "read"}}
+	doc.Parents["tenant-2/admin"] = []string{"tenant-1/reader"}
+	b.Reset()
+	issues, err = ExportCasbin(&b, doc, WithCasbinDomains(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := []string{
+		"global: role is not qualified with a domain",
+		`tenant-2/admin: parent "tenant-1/reader" is not in domain "tenant-2"`,
+	}
+	if len(issues) != len(messages) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	for i, issue := range issues {
+		if issue.Error() != messages[i] {
+			t.Fatalf("%q expected, but %v got", messages[i], issue)
+		}
+	}
+
+	// without an action separator objects and actions are not joined
+	_, issues, err = ImportCasbin(strings.NewReader("p, a, data, read\n"), WithCasbinActionSeparator(""))
+	if err != nil || len(issues) != 1 {
+		t.Fatalf("unexpected issues %v, %v", issues, err)
+	}
+}
@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+// ChangeKind tells what a Change adds or removes.
+type ChangeKind string
+
+const (
+	ChangeRole       ChangeKind = "role"
+	ChangePermission ChangeKind = "permission"
+	ChangeParent     ChangeKind = "parent"
+)
+
+// Change is one difference between two policies.
+type Change struct {
+	Added bool
+	Kind  ChangeKind
+	Role  string
+	// Permission is set for ChangePermission.
+	Permission gorbac.PermissionRecord[string]
+	// Parent is set for ChangeParent.
+	Parent string
+}
+
+func (c Change) String() string {
+	sign := "-"
+	if c.Added {
+		sign = "+"
+	}
+	switch c.Kind {
+	case ChangePermission:
+		return fmt.Sprintf("%s %s permission %s", sign, c.Role, FormatRecord(c.Permission))
+	case ChangeParent:
+		return fmt.Sprintf("%s %s parent %s", sign, c.Role, c.Parent)
+	default:
+		return fmt.Sprintf("%s role %s", sign, c.Role)
+	}
+}
+
+// FormatRecord renders a permission record on one line, e.g.
+// `posts (filter: creator_id == 1)`.
+func FormatRecord(r gorbac.PermissionRecord[string]) string {
+	switch {
+	case r.Sep != "":
+		return fmt.Sprintf("%s (sep: %q)", r.ID, r.Sep)
+	case r.Filter != "":
+		return fmt.Sprintf("%s (filter: %s)", r.ID, r.Filter)
+	default:
+		return r.ID
+	}
+}
+
+// Diff lists the changes turning `from` into `to`, ordered by role. A
+// permission whose record changed is reported as removed and added again.
+func Diff(from, to *Document) []Change {
+	ids := append(from.RoleIDs(), to.RoleIDs()...)
+	for id := range from.Parents {
+		ids = append(ids, id)
+	}
+	for id := range to.Parents {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	var changes []Change
+	for _, id := range ids {
+		oldPerms, inFrom := from.Roles[id]
+		newPerms, inTo := to.Roles[id]
+		if inFrom != inTo {
+			changes = append(changes, Change{Added: inTo, Kind: ChangeRole, Role: id})
+		}
+		for _, r := range oldPerms {
+			if !slices.Contains(newPerms, r) {
+				changes = append(changes, Change{Kind: ChangePermission, Role: id, Permission: r})
+			}
+		}
+		for _, r := range newPerms {
+			if !slices.Contains(oldPerms, r) {
+				changes = append(changes, Change{Added: true, Kind: ChangePermission, Role: id, Permission: r})
+			}
+		}
+		for _, parent := range from.Parents[id] {
+			if !slices.Contains(to.Parents[id], parent) {
+				changes = append(changes, Change{Kind: ChangeParent, Role: id, Parent: parent})
+			}
+		}
+		for _, parent := range to.Parents[id] {
+			if !slices.Contains(from.Parents[id], parent) {
+				changes = append(changes, Change{Added: true, Kind: ChangeParent, Role: id, Parent: parent})
+			}
+		}
+	}
+	return changes
+}
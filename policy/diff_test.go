@@ -0,0 +1,43 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+func TestDiff(t *testing.T) {
+	from := &Document{
+		Roles: map[string][]gorbac.PermissionRecord[string]{
+			"editor": {{ID: "add-text"}, {ID: "posts", Filter: "creator_id == 1"}},
+			"intern": {{ID: "read"}},
+		},
+		Parents: map[string][]string{"editor": {"intern"}},
+	}
+	to := &Document{
+		Roles: map[string][]gorbac.PermissionRecord[string]{
+			"editor": {{ID: "add-text"}, {ID: "posts", Filter: "creator_id == 2"}},
+			"chief":  {{ID: "del-text"}},
+		},
+		Parents: map[string][]string{"chief": {"editor"}},
+	}
+	want := []string{
+		"+ role chief",
+		"+ chief permission del-text",
+		"+ chief parent editor",
+		"- editor permission posts (filter: creator_id == 1)",
+		"+ editor permission posts (filter: creator_id == 2)",
+		"- editor parent intern",
+		"- role intern",
+		"- intern permission read",
+	}
+	changes := Diff(from, to)
+	if len(changes) != len(want) {
+		t.Fatalf("%d changes expected, got %v", len(want), changes)
+	}
+	for i, c := range changes {
+		if c.String() != want[i] {
+			t.Fatalf("change %d: %q expected, got %q", i, want[i], c.String())
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package policy loads, validates and compares gorbac policies stored as JSON.
+//
+// Two layouts are supported. The split layout of examples/persistence keeps
+// permissions and inheritance in separate files:
+//
+//	roles.json: {"editor": ["add-text", "edit-text"], "chief-editor": ["del-text"]}
+//	inher.json: {"chief-editor": ["editor"]}
+//
+// The combined layout keeps both in one Document:
+//
+//	{
+//	  "roles": {"editor": ["add-text", {"id": "posts", "filter": "creator_id == 1"}]},
+//	  "parents": {"chief-editor": ["editor"]}
+//	}
+//
+// Permissions use the gorbac.PermissionRecord form, so a bare string is a
+// standard permission.
+//
+// Policies can also be written in a compact text language, see ParseText
+// and FormatText, and imported from or exported to Casbin CSV policies, see
+// ImportCasbin and ExportCasbin.
+//
+// A Reloader serves a policy file as a gorbac.RBAC and picks up its edits.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+// Document is a policy: the permissions of every role and the parents
+// each role inherits from.
+type Document struct {
+	Roles   map[string][]gorbac.PermissionRecord[string] `json:"roles"`
+	Parents map[string][]string                          `json:"parents,omitempty"`
+}
+
+// Load reads a policy from `spec`, either a combined Document file or the
+// split layout given as "roles.json,inher.json". A single file holding only
+// the roles map is accepted as well, and files ending with TextExt are read
+// with ParseText.
+func Load(spec string) (*Document, error) {
+	if rolesFile, inherFile, ok := strings.Cut(spec, ","); ok {
+		return LoadSplit(rolesFile, inherFile)
+	}
+	data, err := os.ReadFile(spec)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(spec, TextExt) {
+		doc, err := ParseText(data)
+		if err != nil {
+			// file:line:column: message
+			return nil, fmt.Errorf("%s:%w", spec, err)
+		}
+		return doc, nil
+	}
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", spec, err)
+	}
+	return doc, nil
+}
+
+// LoadSplit reads the roles and the inheritance of the split layout.
+func LoadSplit(rolesFile, inherFile string) (*Document, error) {
+	doc := &Document{}
+	if err := readJSON(rolesFile, &doc.Roles); err != nil {
+		return nil, err
+	}
+	if err := readJSON(inherFile, &doc.Parents); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Parse decodes a combined Document, or a bare roles map.
+func Parse(data []byte) (*Document, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, err
+	}
+	doc := &Document{}
+	// a role named "roles" maps to an array, a Document to an object
+	if raw, ok := top["roles"]; ok && bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		if err := json.Unmarshal(data, doc); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+	if err := json.Unmarshal(data, &doc.Roles); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func readJSON(name string, v any) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// RoleIDs returns the sorted IDs of the declared roles.
+func (d *Document) RoleIDs() []string {
+	ids := make([]string, 0, len(d.Roles))
+	for id := range d.Roles {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Build returns a StdRBAC holding the policy. Unlike Validate it stops at the
+// first problem; inheritance cycles are not rejected.
+func (d *Document) Build(ctx context.Context) (*gorbac.StdRBAC[string], error) {
+	rbac, issues := d.build(ctx)
+	if len(issues) > 0 {
+		return nil, issues[0]
+	}
+	return rbac, nil
+}
+
+// Issue describes a problem found by Validate.
+type Issue struct {
+	Role    string
+	Message string
+	// Err is the underlying error, e.g. gorbac.ErrRoleNotExist.
+	Err error
+}
+
+func (i Issue) Error() string {
+	if i.Role == "" {
+		return i.Message
+	}
+	return i.Role + ": " + i.Message
+}
+
+func (i Issue) Unwrap() error {
+	return i.Err
+}
+
+// Validate reports every missing role, invalid permission and inheritance
+// cycle of the policy. A nil result means the policy is valid.
+func (d *Document) Validate(ctx context.Context) []Issue {
+	rbac, issues := d.build(ctx)
+	if err := gorbac.InherCircle(ctx, rbac); err != nil {
+		issues = append(issues, Issue{Message: "inheritance " + err.Error(), Err: err})
+	}
+	return issues
+}
+
+// build adds every valid role and inheritance edge, collecting the issues.
+func (d *Document) build(ctx context.Context) (*gorbac.StdRBAC[string], []Issue) {
+	rbac := gorbac.New[string]()
+	var issues []Issue
+	for _, id := range d.RoleIDs() {
+		role := gorbac.NewRole(id)
+		for _, record := range d.Roles[id] {
+			p, err := record.Permission()
+			if err != nil {
+				issues = append(issues, Issue{Role: id, Message: err.Error(), Err: err})
+				continue
+			}
+			if err := role.Assign(ctx, p); err != nil {
+				issues = append(issues, Issue{Role: id, Message: err.Error(), Err: err})
+			}
+		}
+		if err := rbac.Add(ctx, role); err != nil {
+			issues = append(issues, Issue{Role: id, Message: err.Error(), Err: err})
+		}
+	}
+	children := make([]string, 0, len(d.Parents))
+	for id := range d.Parents {
+		children = append(children, id)
+	}
+	slices.Sort(children)
+	for _, id := range children {
+		if _, ok := d.Roles[id]; !ok {
+			issues = append(issues, Issue{Role: id, Message: "inheriting role is not declared", Err: gorbac.ErrRoleNotExist})
+			continue
+		}
+		for _, parent := range d.Parents[id] {
+			if _, ok := d.Roles[parent]; !ok {
+				issues = append(issues, Issue{Role: id, Message: fmt.Sprintf("parent %q is not declared", parent), Err: gorbac.ErrRoleNotExist})
+				continue
+			}
+			if err := rbac.SetParents(ctx, id, parent); err != nil {
+				issues = append(issues, Issue{Role: id, Message: err.Error(), Err: err})
+			}
+		}
+	}
+	return rbac, issues
+}
+
+// FromRBAC snapshots the roles and inheritance of `rbac`.
+// gorbac.ErrUnsupportedPermission is returned for custom permission types.
+func FromRBAC(ctx context.Context, rbac gorbac.RBAC[string]) (*Document, error) {
+	doc := &Document{
+		Roles:   make(map[string][]gorbac.PermissionRecord[string]),
+		Parents: make(map[string][]string),
+	}
+	err := gorbac.Walk(ctx, rbac, func(role gorbac.Role[string], parents []string) error {
+		records := make([]gorbac.PermissionRecord[string], 0)
+		for _, p := range role.Permissions(ctx) {
+			record, err := gorbac.RecordOf(p)
+			if err != nil {
+				return fmt.Errorf("role %s: %w", role.ID(), err)
+			}
+			records = append(records, record)
+		}
+		slices.SortFunc(records, func(a, b gorbac.PermissionRecord[string]) int {
+			return strings.Compare(a.ID, b.ID)
+		})
+		doc.Roles[role.ID()] = records
+		if len(parents) > 0 {
+			parents = slices.Clone(parents)
+			slices.Sort(parents)
+			doc.Parents[role.ID()] = parents
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,110 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+func TestLoadSplit(t *testing.T) {
+	ctx := context.Background()
+	doc, err := Load("../examples/persistence/roles.json,../examples/persistence/inher.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := doc.Validate(ctx); len(issues) != 0 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	rbac, err := doc.Build(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rbac.IsGranted(ctx, "chief-editor", gorbac.NewPermission("add-text")) {
+		t.Fatal("chief-editor should inherit add-text")
+	}
+}
+
+func TestParse(t *testing.T) {
+	doc, err := Parse([]byte(`{"roles": {"a": ["x", {"id": "y", "filter": "id == 1"}]}, "parents": {"a": ["b"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Roles["a"]) != 2 || doc.Roles["a"][1].Filter != "id == 1" || doc.Parents["a"][0] != "b" {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+	// a roles map with a role named "roles"
+	doc, err = Parse([]byte(`{"roles": ["x"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Roles["roles"]) != 1 {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+
+	name := filepath.Join(t.TempDir(), "policy.json")
+	if err := os.WriteFile(name, []byte(`{"roles": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(name); err == nil {
+		t.Fatal("malformed JSON should be rejected")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+	doc := &Document{
+		Roles: map[string][]gorbac.PermissionRecord[string]{
+			"a": {{ID: "x"}, {ID: "y", Sep: ":", Filter: "id == 1"}},
+			"b": nil,
+			"c": nil,
+		},
+		Parents: map[string][]string{
+			"a":       {"b", "missing"},
+			"b":       {"c"},
+			"c":       {"a"},
+			"unknown": {"a"},
+		},
+	}
+	issues := doc.Validate(ctx)
+	if len(issues) != 4 {
+		t.Fatalf("4 issues expected, got %v", issues)
+	}
+	notExist := 0
+	for _, issue := range issues {
+		if errors.Is(issue, gorbac.ErrRoleNotExist) {
+			notExist++
+		}
+	}
+	if notExist != 2 || !errors.Is(issues[3], gorbac.ErrFoundCircle) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	if _, err := doc.Build(ctx); err == nil {
+		t.Fatal("Build should fail on an invalid permission")
+	}
+}
+
+func TestFromRBAC(t *testing.T) {
+	ctx := context.Background()
+	doc := &Document{
+		Roles: map[string][]gorbac.PermissionRecord[string]{
+			"a": {{ID: "x"}, {ID: "y:z", Sep: ":"}},
+			"b": {{ID: "w", Filter: "id == 1"}},
+		},
+		Parents: map[string][]string{"a": {"b"}},
+	}
+	rbac, err := doc.Build(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := FromRBAC(ctx, rbac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := Diff(doc, snapshot); len(changes) != 0 {
+		t.Fatalf("the snapshot should equal the document, got %v", changes)
+	}
+}
@@ -0,0 +1,285 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fy0/gorbac/v3"
+	"github.com/fy0/gorbac/v3/filter"
+)
+
+// DefaultPollInterval is how often a Reloader checks its files unless
+// WithPollInterval says otherwise.
+const DefaultPollInterval = 2 * time.Second
+
+type reloadConfig struct {
+	interval   time.Duration
+	schema     *filter.Schema
+	engineOpts []filter.EngineOption
+	onError    func(error)
+	onReload   func(*Document)
+	configure  func(*gorbac.StdRBAC[string])
+}
+
+// ReloadOption customizes a Reloader.
+type ReloadOption func(*reloadConfig)
+
+// WithPollInterval sets how often the policy files are checked for changes.
+func WithPollInterval(interval time.Duration) ReloadOption {
+	return func(cfg *reloadConfig) {
+		if interval > 0 {
+			cfg.interval = interval
+		}
+	}
+}
+
+// WithSchema also rejects policies whose filter permissions do not compile
+// against `schema`.
+func WithSchema(schema filter.Schema, engineOpts ...filter.EngineOption) ReloadOption {
+	return func(cfg *reloadConfig) {
+		cfg.schema = &schema
+		cfg.engineOpts = engineOpts
+	}
+}
+
+// WithErrorHandler receives the errors of the reloads done by Run, e.g. an
+// unreadable file or the issues of an invalid policy. The previous policy
+// stays in effect.
+func WithErrorHandler(onError func(error)) ReloadOption {
+	return func(cfg *reloadConfig) {
+		cfg.onError = onError
+	}
+}
+
+// WithReloadHandler is called with every policy swapped in.
+func WithReloadHandler(onReload func(*Document)) ReloadOption {
+	return func(cfg *reloadConfig) {
+		cfg.onReload = onReload
+	}
+}
+
+// WithConfigure is called with every new RBAC before it is swapped in, e.g.
+// to call SetDecisionLogger, SetInstrumentation or SetImplications, which
+// would otherwise be lost on the next reload.
+func WithConfigure(configure func(*gorbac.StdRBAC[string])) ReloadOption {
+	return func(cfg *reloadConfig) {
+		cfg.configure = configure
+	}
+}
+
+// Reloader is a gorbac.RBAC serving the policy of a file and picking up its
+// edits without a restart.
+//
+// Run polls the files for changes. A changed policy is validated (missing
+// roles, inheritance cycles and, with WithSchema, filter compilation) and
+// swapped in atomically only when valid; otherwise the error is reported
+// and the previous policy is kept. Checks never see a partially loaded
+// policy. Replace the files atomically, e.g. by renaming, or a half-written
+// file may be rejected until the next change.
+//
+// Mutations made through the Reloader, and settings made on Current, apply
+// to the current policy and are lost on the next reload; use WithConfigure
+// to set up every policy loaded.
+type Reloader struct {
+	spec    string
+	cfg     reloadConfig
+	engine  *filter.Engine
+	current atomic.Pointer[loaded]
+
+	// mutex serializes reloads
+	mutex sync.Mutex
+	stats []fileStat
+}
+
+type loaded struct {
+	doc  *Document
+	rbac *gorbac.StdRBAC[string]
+}
+
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewReloader loads the policy of `spec`, see Load. It fails when the
+// initial policy is not valid.
+func NewReloader(ctx context.Context, spec string, opts ...ReloadOption) (*Reloader, error) {
+	cfg := reloadConfig{interval: DefaultPollInterval}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+	r := &Reloader{spec: spec, cfg: cfg}
+	if cfg.schema != nil {
+		engine, err := filter.NewEngine(*cfg.schema, cfg.engineOpts...)
+		if err != nil {
+			return nil, err
+		}
+		r.engine = engine
+	}
+	if _, err := r.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload checks the files now. It reports whether a new policy was swapped
+// in; unchanged files are not read again. The error of an invalid policy
+// joins its issues.
+func (r *Reloader) Reload(ctx context.Context) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stats, err := r.statFiles()
+	if err != nil {
+		return false, err
+	}
+	if r.current.Load() != nil && slices.Equal(stats, r.stats) {
+		return false, nil
+	}
+	// a policy failing to load is not retried until the files change again
+	doc, err := Load(r.spec)
+	if err != nil {
+		r.stats = stats
+		return false, err
+	}
+	issues := doc.Validate(ctx)
+	if r.engine != nil {
+		issues = append(issues, doc.filterIssues(r.engine)...)
+	}
+	if len(issues) > 0 {
+		r.stats = stats
+		errs := make([]error, len(issues))
+		for i, issue := range issues {
+			errs[i] = issue
+		}
+		return false, fmt.Errorf("%s: %w", r.spec, errors.Join(errs...))
+	}
+	rbac, err := doc.Build(ctx)
+	if err != nil {
+		return false, err
+	}
+	if r.cfg.configure != nil {
+		r.cfg.configure(rbac)
+	}
+	r.current.Store(&loaded{doc: doc, rbac: rbac})
+	r.stats = stats
+	if r.cfg.onReload != nil {
+		r.cfg.onReload(doc)
+	}
+	return true, nil
+}
+
+func (r *Reloader) statFiles() ([]fileStat, error) {
+	var stats []fileStat
+	for _, name := range strings.Split(r.spec, ",") {
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, fileStat{modTime: info.ModTime(), size: info.Size()})
+	}
+	return stats, nil
+}
+
+// Run polls the files until `ctx` is done.
+func (r *Reloader) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Reload(ctx); err != nil && r.cfg.onError != nil {
+				r.cfg.onError(err)
+			}
+		}
+	}
+}
+
+// filterIssues compiles every filter permission with `engine`.
+func (d *Document) filterIssues(engine *filter.Engine) []Issue {
+	var issues []Issue
+	for _, id := range d.RoleIDs() {
+		for _, record := range d.Roles[id] {
+			if record.Filter == "" {
+				continue
+			}
+			if _, err := engine.Compile(record.Filter); err != nil {
+				issues = append(issues, Issue{Role: id, Message: fmt.Sprintf("filter of %s: %v", record.ID, err), Err: err})
+			}
+		}
+	}
+	return issues
+}
+
+// Document returns the policy in effect.
+func (r *Reloader) Document() *Document {
+	return r.current.Load().doc
+}
+
+// Current returns the RBAC of the policy in effect. Keep calling it rather
+// than holding on to the result, which a reload replaces.
+func (r *Reloader) Current() *gorbac.StdRBAC[string] {
+	return r.current.Load().rbac
+}
+
+// Add a role `role` to the current policy.
+func (r *Reloader) Add(ctx context.Context, role gorbac.Role[string]) error {
+	return r.Current().Add(ctx, role)
+}
+
+// Remove the role by `id` from the current policy.
+func (r *Reloader) Remove(ctx context.Context, id string) error {
+	return r.Current().Remove(ctx, id)
+}
+
+// Get returns the role by `id`.
+func (r *Reloader) Get(ctx context.Context, id string) (gorbac.Role[string], error) {
+	return r.Current().Get(ctx, id)
+}
+
+// RoleIDs returns all role IDs.
+func (r *Reloader) RoleIDs(ctx context.Context) []string {
+	return r.Current().RoleIDs(ctx)
+}
+
+// SetParents bind `parents` to the role `id` in the current policy.
+func (r *Reloader) SetParents(ctx context.Context, id string, parents ...string) error {
+	return r.Current().SetParents(ctx, id, parents...)
+}
+
+// GetParents return `parents` of the role `id`.
+func (r *Reloader) GetParents(ctx context.Context, id string) ([]string, error) {
+	return r.Current().GetParents(ctx, id)
+}
+
+// RemoveParents unbind `parents` from the role `id` in the current policy.
+func (r *Reloader) RemoveParents(ctx context.Context, id string, parents ...string) error {
+	return r.Current().RemoveParents(ctx, id, parents...)
+}
+
+// IsGranted tests if the role `id` has permission `p`.
+func (r *Reloader) IsGranted(ctx context.Context, id string, p gorbac.Permission[string]) bool {
+	return r.Current().IsGranted(ctx, id, p)
+}
+
+// Check tests if the role `id` has permission `p`, see gorbac.StdRBACOf.Check.
+func (r *Reloader) Check(ctx context.Context, id string, p gorbac.Permission[string]) (bool, error) {
+	return r.Current().Check(ctx, id, p)
+}
+
+// BatchGranted tests every permission against the role set `roles` at once,
+// see gorbac.StdRBACOf.BatchGranted.
+func (r *Reloader) BatchGranted(ctx context.Context, roles []string, permissions ...gorbac.Permission[string]) []bool {
+	return r.Current().BatchGranted(ctx, roles, permissions...)
+}
@@ -0,0 +1,184 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fy0/gorbac/v3"
+	"github.com/fy0/gorbac/v3/filter"
+)
+
+// writePolicy replaces the file atomically with a newer modification time,
+// so that a change is seen even on filesystems with a coarse time
+// resolution.
+func writePolicy(t *testing.T, name, data string) {
+	t.Helper()
+	mtime := time.Now()
+	if info, err := os.Stat(name); err == nil && !info.ModTime().Before(mtime) {
+		mtime = info.ModTime().Add(time.Second)
+	}
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tmp, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloader(t *testing.T) {
+	ctx := context.Background()
+	schema, err := filter.SchemaFromJSON([]byte(`{"name": "orders", "table": "orders", "fields": {"owner_id": {"type": "int"}}, "variables": {"uid": "int"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(t.TempDir(), "policy.json")
+	writePolicy(t, name, `{"roles": {"viewer": ["read"], "editor": ["write"]}, "parents": {"editor": ["viewer"]}}`)
+	var reloaded int
+	r, err := NewReloader(ctx, name, WithSchema(schema), WithReloadHandler(func(*Document) { reloaded++ }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var _ gorbac.RBAC[string] = r
+	read, write := gorbac.NewPermission("read"), gorbac.NewPermission("write")
+	if !r.IsGranted(ctx, "editor", read) || reloaded != 1 {
+		t.Fatal("editor should inherit read")
+	}
+	if ok, err := r.Reload(ctx); ok || err != nil {
+		t.Fatalf("unchanged files should not reload, got %v, %v", ok, err)
+	}
+
+	writePolicy(t, name, `{"roles": {"viewer": ["read"], "editor": ["write", {"id": "orders", "filter": "owner_id == uid"}]}}`)
+	if ok, err := r.Reload(ctx); !ok || err != nil {
+		t.Fatalf("the policy should reload, got %v, %v", ok, err)
+	}
+	if r.IsGranted(ctx, "editor", read) || !r.IsGranted(ctx, "editor", write) || reloaded != 2 {
+		t.Fatal("the new policy should be in effect")
+	}
+
+	invalid := map[string]string{
+		"cycle":          `{"roles": {"a": [], "b": []}, "parents": {"a": ["b"], "b": ["a"]}}`,
+		"missing parent": `{"roles": {"a": []}, "parents": {"a": ["b"]}}`,
+		"filter":         `{"roles": {"a": [{"id": "orders", "filter": "unknown_column == 1"}]}}`,
+		"syntax":         `{"roles": `,
+	}
+	for desc, data := range invalid {
+		writePolicy(t, name, data)
+		if ok, err := r.Reload(ctx); ok || err == nil {
+			t.Fatalf("%s: the policy should be rejected", desc)
+		}
+		if !r.IsGranted(ctx, "editor", write) || len(r.Document().Roles) != 2 {
+			t.Fatalf("%s: the previous policy should be kept", desc)
+		}
+	}
+	if ok, err := r.Reload(ctx); ok || err != nil {
+		t.Fatalf("an invalid policy should not be retried, got %v, %v", ok, err)
+	}
+
+	writePolicy(t, name, `{"roles": {"a": [], "b": []}, "parents": {"a": ["b"], "b": ["a"]}}`)
+	_, err = r.Reload(ctx)
+	if !errors.Is(err, gorbac.ErrFoundCircle) || !strings.Contains(err.Error(), "circle") {
+		t.Fatalf("a cycle should be reported, got %v", err)
+	}
+	if _, err := NewReloader(ctx, name); err == nil {
+		t.Fatal("an invalid initial policy should fail")
+	}
+}
+
+func TestReloaderRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	name := filepath.Join(t.TempDir(), "policy.json")
+	writePolicy(t, name, `{"roles": {"viewer": ["read"]}}`)
+
+	var mutex sync.Mutex
+	var errs []error
+	swapped := make(chan *Document, 4)
+	r, err := NewReloader(ctx, name, WithPollInterval(time.Millisecond),
+		WithErrorHandler(func(err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			errs = append(errs, err)
+		}),
+		WithReloadHandler(func(doc *Document) { swapped <- doc }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-swapped
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	writePolicy(t, name, `{"roles": {"viewer": ["read", "list"]}}`)
+	select {
+	case doc := <-swapped:
+		if len(doc.Roles["viewer"]) != 2 {
+			t.Fatalf("unexpected document %+v", doc)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the edit should be picked up")
+	}
+	if !r.IsGranted(ctx, "viewer", gorbac.NewPermission("list")) {
+		t.Fatal("viewer should be granted list")
+	}
+
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mutex.Lock()
+		n := len(errs)
+		mutex.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the missing file should be reported")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !r.IsGranted(ctx, "viewer", gorbac.NewPermission("list")) {
+		t.Fatal("the previous policy should be kept")
+	}
+	cancel()
+	<-done
+}
+
+func TestReloaderConfigure(t *testing.T) {
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "policy.json")
+	writePolicy(t, name, `{"roles": {"viewer": ["read"]}}`)
+	var decisions []gorbac.Decision[string, string]
+	logger := gorbac.DecisionLoggerFunc[string, string](func(_ context.Context, d gorbac.Decision[string, string]) {
+		decisions = append(decisions, d)
+	})
+	r, err := NewReloader(ctx, name, WithConfigure(func(rbac *gorbac.StdRBAC[string]) {
+		rbac.SetDecisionLogger(logger)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := gorbac.NewPermission("read")
+	r.IsGranted(ctx, "viewer", read)
+
+	writePolicy(t, name, `{"roles": {"viewer": ["write"]}}`)
+	if ok, err := r.Reload(ctx); !ok || err != nil {
+		t.Fatalf("the policy should reload, got %v, %v", ok, err)
+	}
+	r.IsGranted(ctx, "viewer", read)
+	if len(decisions) != 2 || !decisions[0].Granted || decisions[1].Granted {
+		t.Fatalf("the logger should survive the reload, got %+v", decisions)
+	}
+}
@@ -0,0 +1,497 @@
+package policy
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+// TextExt is the file extension Load reads as the text policy language.
+const TextExt = ".rbac"
+
+// TextError is a syntax error of the text policy language, at a 1-based
+// line and column (counted in characters).
+type TextError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *TextError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNewline
+	tokWord
+	tokString
+	tokLBrace
+	tokRBrace
+	tokComma
+)
+
+type token struct {
+	kind      tokenKind
+	text      string
+	line, col int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokNewline:
+		return "end of line"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+type lexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+func (l *lexer) errorf(line, col int, format string, args ...any) error {
+	return &TextError{Line: line, Column: col, Message: fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) peekRune() rune {
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return r
+}
+
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+	l.pos += size
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(`{},#"`, r)
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		r := l.peekRune()
+		if r == '#' {
+			for l.pos < len(l.src) && l.peekRune() != '\n' {
+				l.advance()
+			}
+			continue
+		}
+		if r == '\n' || !unicode.IsSpace(r) {
+			break
+		}
+		l.advance()
+	}
+	t := token{line: l.line, col: l.col}
+	if l.pos >= len(l.src) {
+		return t, nil
+	}
+	start := l.pos
+	switch r := l.advance(); r {
+	case '\n':
+		t.kind = tokNewline
+	case '{':
+		t.kind, t.text = tokLBrace, "{"
+	case '}':
+		t.kind, t.text = tokRBrace, "}"
+	case ',':
+		t.kind, t.text = tokComma, ","
+	case '"':
+		for {
+			if l.pos >= len(l.src) || l.peekRune() == '\n' {
+				return t, l.errorf(t.line, t.col, "unterminated string")
+			}
+			c := l.advance()
+			if c == '\\' && l.pos < len(l.src) {
+				l.advance()
+			} else if c == '"' {
+				break
+			}
+		}
+		s, err := strconv.Unquote(l.src[start:l.pos])
+		if err != nil {
+			return t, l.errorf(t.line, t.col, "invalid string %s", l.src[start:l.pos])
+		}
+		t.kind, t.text = tokString, s
+	default:
+		for l.pos < len(l.src) && isWordRune(l.peekRune()) {
+			l.advance()
+		}
+		t.kind, t.text = tokWord, l.src[start:l.pos]
+	}
+	return t, nil
+}
+
+// restOfLine returns the text up to the end of the line or a comment
+// outside of string literals, trimmed.
+func (l *lexer) restOfLine() string {
+	start := l.pos
+	var quote rune
+	for l.pos < len(l.src) {
+		r := l.peekRune()
+		if r == '\n' || (r == '#' && quote == 0) {
+			break
+		}
+		l.advance()
+		switch {
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+		case quote != 0 && r == '\\' && l.pos < len(l.src):
+			l.advance()
+		case r == quote:
+			quote = 0
+		}
+	}
+	return strings.TrimSpace(l.src[start:l.pos])
+}
+
+type parser struct {
+	lex  lexer
+	tok  token
+	sep  string
+	doc  *Document
+	decl map[string]token
+	refs []token
+}
+
+func (p *parser) advance() error {
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = t
+	return nil
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return p.lex.errorf(t.line, t.col, format, args...)
+}
+
+func (p *parser) skipNewlines() error {
+	for p.tok.kind == tokNewline {
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// name reads a role or permission name.
+func (p *parser) name(what string) (token, error) {
+	t := p.tok
+	if t.kind != tokWord && t.kind != tokString {
+		return t, p.errorf(t, "expected %s, found %s", what, t)
+	}
+	if t.text == "" {
+		return t, p.errorf(t, "empty %s", what)
+	}
+	return t, p.advance()
+}
+
+func (p *parser) endOfStatement() error {
+	switch p.tok.kind {
+	case tokNewline:
+		return p.advance()
+	case tokEOF:
+		return nil
+	default:
+		return p.errorf(p.tok, "unexpected %s", p.tok)
+	}
+}
+
+// ParseText parses a policy written in the text policy language:
+//
+//	# comments run to the end of the line
+//	separator ":"
+//
+//	role viewer {
+//		read
+//		posts:*                      # layered: posts and everything below
+//	}
+//
+//	role editor inherits viewer, author {
+//		posts:edit, "plain:id"       # quoted names are standard permissions
+//		orders where owner_id == uid # a filter, up to the end of the line
+//	}
+//
+//	role guest
+//
+// After a separator statement, unquoted permissions containing the
+// separator are layered permissions, which always cover every permission
+// below them. A trailing "*" layer only marks a name as layered: "posts:*"
+// is the layered permission "posts", while "posts:edit:*" and "posts:edit"
+// are the same permission. FormatText writes the "*" only for single-layer
+// names. Roles may be declared in any order, but only once, and
+// every parent must be declared. Errors are *TextError values.
+func ParseText(data []byte) (*Document, error) {
+	p := &parser{
+		lex:  lexer{src: string(data), line: 1, col: 1},
+		doc:  &Document{Roles: make(map[string][]gorbac.PermissionRecord[string])},
+		decl: make(map[string]token),
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	for {
+		if err := p.skipNewlines(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokEOF {
+			break
+		}
+		if p.tok.kind != tokWord {
+			return nil, p.errorf(p.tok, "expected role or separator, found %s", p.tok)
+		}
+		var err error
+		switch p.tok.text {
+		case "role":
+			err = p.role()
+		case "separator":
+			err = p.separator()
+		default:
+			err = p.errorf(p.tok, "expected role or separator, found %s", p.tok)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, ref := range p.refs {
+		if _, ok := p.decl[ref.text]; !ok {
+			return nil, p.errorf(ref, "role %q is not declared", ref.text)
+		}
+	}
+	return p.doc, nil
+}
+
+func (p *parser) separator() error {
+	if err := p.advance(); err != nil {
+		return err
+	}
+	if p.tok.kind != tokString || p.tok.text == "" {
+		return p.errorf(p.tok, "expected separator string, found %s", p.tok)
+	}
+	p.sep = p.tok.text
+	if err := p.advance(); err != nil {
+		return err
+	}
+	return p.endOfStatement()
+}
+
+func (p *parser) role() error {
+	if err := p.advance(); err != nil {
+		return err
+	}
+	id, err := p.name("role name")
+	if err != nil {
+		return err
+	}
+	if prev, ok := p.decl[id.text]; ok {
+		return p.errorf(id, "role %q already declared at %d:%d", id.text, prev.line, prev.col)
+	}
+	p.decl[id.text] = id
+	records := []gorbac.PermissionRecord[string]{}
+
+	if p.tok.kind == tokWord && p.tok.text == "inherits" {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if p.doc.Parents == nil {
+			p.doc.Parents = make(map[string][]string)
+		}
+		for {
+			parent, err := p.name("parent role")
+			if err != nil {
+				return err
+			}
+			p.refs = append(p.refs, parent)
+			p.doc.Parents[id.text] = append(p.doc.Parents[id.text], parent.text)
+			if p.tok.kind != tokComma {
+				break
+			}
+			if err := p.advance(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.tok.kind == tokLBrace {
+		open := p.tok
+		if err := p.advance(); err != nil {
+			return err
+		}
+		seen := make(map[string]token)
+		for {
+			for p.tok.kind == tokNewline || p.tok.kind == tokComma {
+				if err := p.advance(); err != nil {
+					return err
+				}
+			}
+			if p.tok.kind == tokRBrace {
+				break
+			}
+			if p.tok.kind == tokEOF {
+				return p.errorf(open, "missing } for role %q", id.text)
+			}
+			t, record, err := p.permission()
+			if err != nil {
+				return err
+			}
+			if prev, ok := seen[record.ID]; ok {
+				return p.errorf(t, "permission %q already listed at %d:%d", record.ID, prev.line, prev.col)
+			}
+			seen[record.ID] = t
+			records = append(records, record)
+			if p.tok.kind != tokComma && p.tok.kind != tokNewline && p.tok.kind != tokRBrace {
+				return p.errorf(p.tok, "unexpected %s", p.tok)
+			}
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	p.doc.Roles[id.text] = records
+	return p.endOfStatement()
+}
+
+func (p *parser) permission() (token, gorbac.PermissionRecord[string], error) {
+	t, err := p.name("permission")
+	if err != nil {
+		return t, gorbac.PermissionRecord[string]{}, err
+	}
+	record := gorbac.PermissionRecord[string]{ID: t.text}
+	if t.kind == tokWord && p.sep != "" && strings.Contains(t.text, p.sep) {
+		record.ID = strings.TrimSuffix(t.text, p.sep+"*")
+		record.Sep = p.sep
+		if record.ID == "" || strings.Contains(record.ID, "*") {
+			return t, record, p.errorf(t, "invalid layered permission %q", t.text)
+		}
+	}
+	if p.tok.kind == tokWord && p.tok.text == "where" {
+		where := p.tok
+		if record.Sep != "" {
+			return t, record, p.errorf(where, "layered permission %q cannot have a filter", t.text)
+		}
+		record.Filter = p.lex.restOfLine()
+		if record.Filter == "" {
+			return t, record, p.errorf(where, "missing filter expression after where")
+		}
+		if err := p.advance(); err != nil {
+			return t, record, err
+		}
+	}
+	return t, record, nil
+}
+
+var keywords = []string{"role", "inherits", "separator", "where"}
+
+// formatName writes `s` bare when it reads back as the same word.
+func formatName(s, sep string) string {
+	if s == "" || slices.Contains(keywords, s) || (sep != "" && strings.Contains(s, sep)) {
+		return strconv.Quote(s)
+	}
+	for _, r := range s {
+		if !isWordRune(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// FormatText writes `doc` in the text policy language, roles sorted by ID.
+// A separator statement precedes every role using layered permissions with
+// another separator than the previous one; a role mixing separators cannot
+// be written.
+func FormatText(w io.Writer, doc *Document) error {
+	var b strings.Builder
+	sep := ""
+	for _, id := range doc.RoleIDs() {
+		records := doc.Roles[id]
+		roleSep := ""
+		for _, record := range records {
+			if record.Sep == "" {
+				continue
+			}
+			if roleSep != "" && record.Sep != roleSep {
+				return fmt.Errorf("role %q mixes the separators %q and %q", id, roleSep, record.Sep)
+			}
+			if record.Filter != "" {
+				return fmt.Errorf("role %q: permission %q: sep and filter are exclusive", id, record.ID)
+			}
+			roleSep = record.Sep
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		if roleSep != "" && roleSep != sep {
+			sep = roleSep
+			fmt.Fprintf(&b, "separator %s\n\n", strconv.Quote(sep))
+		}
+
+		b.WriteString("role " + formatName(id, ""))
+		if parents := doc.Parents[id]; len(parents) > 0 {
+			names := make([]string, len(parents))
+			for i, parent := range parents {
+				names[i] = formatName(parent, "")
+			}
+			b.WriteString(" inherits " + strings.Join(names, ", "))
+		}
+		if len(records) == 0 {
+			b.WriteByte('\n')
+			continue
+		}
+		b.WriteString(" {\n")
+		for _, record := range records {
+			b.WriteByte('\t')
+			switch {
+			case record.Sep != "":
+				name := record.ID
+				if !strings.Contains(name, sep) {
+					name += sep + "*"
+				}
+				if strings.Contains(record.ID, "*") || formatName(name, "") != name {
+					return fmt.Errorf("role %q: layered permission %q cannot be written", id, record.ID)
+				}
+				b.WriteString(name)
+			default:
+				b.WriteString(formatName(record.ID, sep))
+				if record.Filter != "" {
+					lex := lexer{src: record.Filter}
+					if lex.restOfLine() != strings.TrimSpace(record.Filter) {
+						return fmt.Errorf("role %q: filter of %q does not fit on one line", id, record.ID)
+					}
+					b.WriteString(" where " + strings.TrimSpace(record.Filter))
+				}
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString("}\n")
+	}
+	for id := range doc.Parents {
+		if _, ok := doc.Roles[id]; !ok {
+			return fmt.Errorf("role %q inherits but is not declared", id)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,203 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+const textPolicy = `# editorial roles
+separator ":"
+
+role viewer {
+	read
+	posts:*   # every posts permission
+}
+
+role editor inherits viewer, author {
+	posts:edit, "plain:id"
+	orders where owner_id == uid && region != "#eu" # own orders only
+}
+
+role author
+role "chief editor" inherits editor
+`
+
+func TestParseText(t *testing.T) {
+	ctx := context.Background()
+	doc, err := ParseText([]byte(textPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]gorbac.PermissionRecord[string]{
+		"viewer": {{ID: "read"}, {ID: "posts", Sep: ":"}},
+		"editor": {{ID: "posts:edit", Sep: ":"}, {ID: "plain:id"}, {ID: "orders", Filter: `owner_id == uid && region != "#eu"`}},
+		"author": {},
+	}
+	for id, records := range expected {
+		if got := doc.Roles[id]; len(got) != len(records) {
+			t.Fatalf("%s: unexpected permissions %+v", id, got)
+		}
+		for i, r := range records {
+			if doc.Roles[id][i] != r {
+				t.Fatalf("%s: %+v expected, but %+v got", id, r, doc.Roles[id][i])
+			}
+		}
+	}
+	if p := doc.Parents["editor"]; len(p) != 2 || p[0] != "viewer" || p[1] != "author" {
+		t.Fatalf("unexpected parents %v", p)
+	}
+	if issues := doc.Validate(ctx); len(issues) != 0 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+
+	rbac, err := doc.Build(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rbac.IsGranted(ctx, "chief editor", gorbac.NewLayerPermission("posts:delete", ":")) {
+		t.Fatal("chief editor should inherit posts:*")
+	}
+	if !rbac.IsGranted(ctx, "editor", gorbac.NewPermission("plain:id")) {
+		t.Fatal("editor should be granted plain:id")
+	}
+	exprs, err := gorbac.FilterExprsForRoles(ctx, rbac, []string{"editor"}, []gorbac.Permission[string]{gorbac.NewPermission("orders")})
+	if err != nil || len(exprs) != 1 || !strings.Contains(exprs[0], "owner_id == uid") {
+		t.Fatalf("unexpected filters %v, %v", exprs, err)
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	doc, err := ParseText([]byte(textPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := FormatText(&b, doc); err != nil {
+		t.Fatal(err)
+	}
+	expected := `role author
+
+role "chief editor" inherits editor
+
+separator ":"
+
+role editor inherits viewer, author {
+	posts:edit
+	"plain:id"
+	orders where owner_id == uid && region != "#eu"
+}
+
+role viewer {
+	read
+	posts:*
+}
+`
+	if b.String() != expected {
+		t.Fatalf("unexpected text:\n%s", b.String())
+	}
+	again, err := ParseText([]byte(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := Diff(doc, again); len(changes) != 0 {
+		t.Fatalf("the formatted policy should parse back, got %v", changes)
+	}
+
+	// JSON policies format as well
+	doc, err = Load("../examples/persistence/roles.json,../examples/persistence/inher.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	if err := FormatText(&b, doc); err != nil {
+		t.Fatal(err)
+	}
+	again, err = ParseText([]byte(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := Diff(doc, again); len(changes) != 0 {
+		t.Fatalf("the formatted policy should parse back, got %v", changes)
+	}
+
+	mixed := &Document{Roles: map[string][]gorbac.PermissionRecord[string]{
+		"a": {{ID: "x:y", Sep: ":"}, {ID: "x/y", Sep: "/"}},
+	}}
+	if err := FormatText(&b, mixed); err == nil {
+		t.Fatal("mixed separators should fail")
+	}
+}
+
+func TestParseTextErrors(t *testing.T) {
+	cases := []struct {
+		src          string
+		line, column int
+		message      string
+	}{
+		{"rol viewer", 1, 1, `expected role or separator, found "rol"`},
+		{"role viewer {\n\tread\n", 1, 13, `missing } for role "viewer"`},
+		{"role a inherits b", 1, 17, `role "b" is not declared`},
+		{"role a\n\nrole a", 3, 6, `role "a" already declared at 1:6`},
+		{"role a {\n\tread, read\n}", 2, 8, `permission "read" already listed at 2:2`},
+		{"role a {\n\torders where\n}", 2, 9, "missing filter expression after where"},
+		{"role a {\n\tread write\n}", 2, 7, `unexpected "write"`},
+		{"role \"a", 1, 6, "unterminated string"},
+		{"separator :", 1, 11, `expected separator string, found ":"`},
+		{"separator \":\"\nrole a {\n\tx:* where y\n}", 3, 6, `layered permission "x:*" cannot have a filter`},
+		{"role été {\n\t{\n}", 2, 2, `expected permission, found "{"`},
+	}
+	for _, c := range cases {
+		_, err := ParseText([]byte(c.src))
+		var te *TextError
+		if !errors.As(err, &te) {
+			t.Fatalf("%q: TextError expected, but %v got", c.src, err)
+		}
+		if te.Line != c.line || te.Column != c.column || te.Message != c.message {
+			t.Fatalf("%q: %d:%d: %s expected, but %v got", c.src, c.line, c.column, c.message, err)
+		}
+	}
+}
+
+func TestParseTextTrailingLayer(t *testing.T) {
+	doc, err := ParseText([]byte("separator \":\"\nrole a {\n\tposts:edit:*\n}\nrole b {\n\tposts:edit\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := doc.Roles["a"], doc.Roles["b"]; len(a) != 1 || len(b) != 1 || a[0] != b[0] {
+		t.Fatalf("posts:edit:* and posts:edit should be the same permission, got %+v and %+v", a, b)
+	}
+	var text strings.Builder
+	if err := FormatText(&text, doc); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(text.String(), "*") {
+		t.Fatalf("the trailing layer should not be written back:\n%s", text.String())
+	}
+}
+
+func TestLoadText(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "policy"+TextExt)
+	if err := os.WriteFile(name, []byte("role a {\n\t}}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load(name)
+	if err == nil || err.Error() != name+":2:3: unexpected \"}\"" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := os.WriteFile(name, []byte(textPolicy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := Load(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Roles) != 4 {
+		t.Fatalf("unexpected roles %v", doc.RoleIDs())
+	}
+}
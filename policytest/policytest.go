@@ -0,0 +1,261 @@
+// Package policytest runs policy-as-code test files against a policy.
+//
+// A test file names the policy (see policy.Load), an optional filter schema
+// (see filter.SchemaSpec) and the expected decisions:
+//
+//	{
+//	  "policy": "policy.json",
+//	  "schema": "schema.json",
+//	  "cases": [
+//	    {"role": "editor", "permission": "add-text", "granted": true},
+//	    {"role": "photographer", "permission": "add-text", "granted": false},
+//	    {"role": "sales", "permission": "orders", "bindings": {"uid": 7}, "rows": [
+//	      {"row": {"owner_id": 7}, "visible": true},
+//	      {"row": {"owner_id": 8}, "visible": false}
+//	    ]}
+//	  ]
+//	}
+//
+// Paths are relative to the test file. Row cases compile the data scope of the
+// roles with gorbac.FilterExprsForRoles and gorbac.NewFilterProgramFromCEL and
+// evaluate it against each row merged with the bindings.
+//
+// From `go test`:
+//
+//	func TestPolicy(t *testing.T) {
+//		policytest.RunFile(t, "testdata/policy_test.json")
+//	}
+//
+// From the command line: `gorbac test testdata/policy_test.json`.
+package policytest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fy0/gorbac/v3"
+	"github.com/fy0/gorbac/v3/filter"
+	"github.com/fy0/gorbac/v3/policy"
+)
+
+// Suite is a policy test file.
+type Suite struct {
+	// Policy is a policy.Load spec.
+	Policy string `json:"policy"`
+	// Schema is a filter.SchemaSpec file, required by row cases.
+	Schema string `json:"schema,omitempty"`
+	Cases  []Case `json:"cases"`
+}
+
+// Case is one expectation. Granted and Rows may be combined.
+type Case struct {
+	Name string `json:"name,omitempty"`
+	// Role is a shorthand for a single entry of Roles.
+	Role  string   `json:"role,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+	// Permission is the requested permission, layered when Sep is set.
+	Permission string `json:"permission"`
+	Sep        string `json:"sep,omitempty"`
+	// Granted, when set, is the expected result of gorbac.AnyGranted.
+	Granted  *bool           `json:"granted,omitempty"`
+	Bindings filter.Bindings `json:"bindings,omitempty"`
+	Rows     []Row           `json:"rows,omitempty"`
+}
+
+// Row is a data row and whether the data scope of the case must include it.
+type Row struct {
+	Row     filter.Bindings `json:"row"`
+	Visible bool            `json:"visible"`
+}
+
+func (c Case) roles() []string {
+	if c.Role != "" {
+		return append([]string{c.Role}, c.Roles...)
+	}
+	return c.Roles
+}
+
+func (c Case) permission() gorbac.Permission[string] {
+	if c.Sep != "" {
+		return gorbac.NewLayerPermission(c.Permission, c.Sep)
+	}
+	return gorbac.NewPermission(c.Permission)
+}
+
+// String names the case in failures: its Name, or a summary of it.
+func (c Case) String() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return strings.Join(c.roles(), ",") + " " + c.Permission
+}
+
+// Failure is a case whose expectation does not hold.
+type Failure struct {
+	// Index is the position of the case in the suite.
+	Index   int
+	Case    string
+	Message string
+}
+
+func (f Failure) String() string {
+	return fmt.Sprintf("case %d (%s): %s", f.Index, f.Case, f.Message)
+}
+
+// Result summarises a run.
+type Result struct {
+	Cases    int
+	Failures []Failure
+}
+
+// OK reports whether every case passed.
+func (r Result) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// Load reads the test file `name` and resolves its paths against the
+// directory of the file.
+func Load(name string) (*Suite, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var s Suite
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	dir := filepath.Dir(name)
+	if s.Policy != "" {
+		specs := strings.Split(s.Policy, ",")
+		for i, spec := range specs {
+			specs[i] = resolve(dir, spec)
+		}
+		s.Policy = strings.Join(specs, ",")
+	}
+	if s.Schema != "" {
+		s.Schema = resolve(dir, s.Schema)
+	}
+	return &s, nil
+}
+
+func resolve(dir, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+// Run loads the policy and schema of the suite and runs its cases.
+// Errors report an unusable suite, e.g. a policy with an inheritance cycle;
+// failed expectations are in the Result.
+func (s *Suite) Run(ctx context.Context) (Result, error) {
+	if s.Policy == "" {
+		return Result{}, fmt.Errorf("policy is required")
+	}
+	doc, err := policy.Load(s.Policy)
+	if err != nil {
+		return Result{}, err
+	}
+	rbac, err := doc.Build(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	// a cycle would make the checks recurse forever
+	if err := gorbac.InherCircle(ctx, rbac); err != nil {
+		return Result{}, fmt.Errorf("%s: inheritance %w", s.Policy, err)
+	}
+	var schema *filter.Schema
+	if s.Schema != "" {
+		data, err := os.ReadFile(s.Schema)
+		if err != nil {
+			return Result{}, err
+		}
+		sc, err := filter.SchemaFromJSON(data)
+		if err != nil {
+			return Result{}, fmt.Errorf("%s: %w", s.Schema, err)
+		}
+		schema = &sc
+	}
+	return Run(ctx, rbac, schema, s.Cases), nil
+}
+
+// Run checks `cases` against `rbac`. `schema` may be nil when no case has rows.
+func Run(ctx context.Context, rbac gorbac.RBAC[string], schema *filter.Schema, cases []Case) Result {
+	result := Result{Cases: len(cases)}
+	for i, c := range cases {
+		fail := func(format string, args ...any) {
+			result.Failures = append(result.Failures, Failure{
+				Index:   i,
+				Case:    c.String(),
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+		roles, p := c.roles(), c.permission()
+		if len(roles) == 0 {
+			fail("no role")
+			continue
+		}
+		if c.Granted != nil {
+			if granted := gorbac.AnyGranted(ctx, rbac, roles, p); granted != *c.Granted {
+				fail("granted = %t, want %t", granted, *c.Granted)
+			}
+		}
+		if len(c.Rows) == 0 {
+			continue
+		}
+		if schema == nil {
+			fail("rows need a schema")
+			continue
+		}
+		exprs, err := gorbac.FilterExprsForRoles(ctx, rbac, roles, []gorbac.Permission[string]{p})
+		if err != nil {
+			fail("%v", err)
+			continue
+		}
+		program, err := gorbac.NewFilterProgramFromCEL(*schema, exprs)
+		if err != nil {
+			fail("%v", err)
+			continue
+		}
+		for j, row := range c.Rows {
+			vars := maps.Clone(c.Bindings)
+			if vars == nil {
+				vars = filter.Bindings{}
+			}
+			maps.Copy(vars, row.Row)
+			visible, err := program.IsCondGranted(vars)
+			if err != nil {
+				fail("row %d: %v", j, err)
+				continue
+			}
+			if visible != row.Visible {
+				fail("row %d %v: visible = %t, want %t (filter: %s)", j, map[string]any(row.Row), visible, row.Visible,
+					strings.Join(exprs, " || "))
+			}
+		}
+	}
+	return result
+}
+
+// RunFile loads and runs the test file `name`, reporting every failure
+// through `t`.
+func RunFile(t testing.TB, name string) {
+	t.Helper()
+	s, err := Load(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	for _, f := range result.Failures {
+		t.Errorf("%s: %s", name, f)
+	}
+}
@@ -0,0 +1,63 @@
+package policytest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+func TestRunFile(t *testing.T) {
+	RunFile(t, "testdata/policy_test.json")
+}
+
+func TestFailures(t *testing.T) {
+	s, err := Load("testdata/failing_test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OK() || result.Cases != 3 || len(result.Failures) != 3 {
+		t.Fatalf("3 failures expected, got %v", result.Failures)
+	}
+	for i, want := range []string{"granted = false, want true", "rows need a schema", "no role"} {
+		if f := result.Failures[i]; f.Index != i || !strings.Contains(f.String(), want) {
+			t.Fatalf("failure %d: %q expected, got %q", i, want, f)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	s, err := Load("testdata/policy_test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Policy != "testdata/policy.json" || s.Schema != "testdata/schema.json" {
+		t.Fatalf("paths should be resolved against the test file, got %q and %q", s.Policy, s.Schema)
+	}
+	if _, err := Load("testdata/missing.json"); err == nil {
+		t.Fatal("a missing file should be reported")
+	}
+	if _, err := (&Suite{}).Run(context.Background()); err == nil {
+		t.Fatal("a suite without policy should be rejected")
+	}
+}
+
+func TestCyclicPolicy(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "cyclic.json")
+	if err := os.WriteFile(name, []byte(`{"roles": {"a": [], "b": []}, "parents": {"a": ["b"], "b": ["a"]}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	granted := true
+	s := &Suite{Policy: name, Cases: []Case{{Role: "a", Permission: "z", Granted: &granted}}}
+	if _, err := s.Run(context.Background()); !errors.Is(err, gorbac.ErrFoundCircle) {
+		t.Fatalf("the cycle should be rejected before the cases run, got %v", err)
+	}
+}
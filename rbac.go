@@ -19,7 +19,9 @@ package gorbac
 import (
 	"context"
 	"errors"
+	"slices"
 	"sync"
+	"time"
 )
 
 var (
@@ -30,38 +32,88 @@ var (
 	empty        = struct{}{}
 )
 
-// RBAC defines the role-based access control contract.
-type RBAC[T comparable] interface {
-	Add(ctx context.Context, role Role[T]) error
-	Remove(ctx context.Context, id T) error
-	Get(ctx context.Context, id T) (Role[T], error)
-	RoleIDs(ctx context.Context) []T
-	SetParents(ctx context.Context, id T, parents ...T) error
-	GetParents(ctx context.Context, id T) ([]T, error)
-	RemoveParents(ctx context.Context, id T, parents ...T) error
-	IsGranted(ctx context.Context, roleID T, permission Permission[T]) bool
+// RBACOf defines the role-based access control contract.
+// R is the type of role ID and P is the type of permission ID.
+type RBACOf[R, P comparable] interface {
+	Add(ctx context.Context, role RoleOf[R, P]) error
+	Remove(ctx context.Context, id R) error
+	Get(ctx context.Context, id R) (RoleOf[R, P], error)
+	RoleIDs(ctx context.Context) []R
+	SetParents(ctx context.Context, id R, parents ...R) error
+	GetParents(ctx context.Context, id R) ([]R, error)
+	RemoveParents(ctx context.Context, id R, parents ...R) error
+	IsGranted(ctx context.Context, roleID R, permission Permission[P]) bool
 }
 
-// StdRBAC object, in most cases it should be used as a singleton.
-type StdRBAC[T comparable] struct {
-	mutex   sync.RWMutex
-	roles   Roles[T]
-	parents map[T]map[T]struct{}
+// RBAC is the role-based access control contract where role IDs and
+// permission IDs share the type T.
+type RBAC[T comparable] = RBACOf[T, T]
+
+// StdRBACOf object, in most cases it should be used as a singleton.
+// R is the type of role ID and P is the type of permission ID.
+type StdRBACOf[R, P comparable] struct {
+	mutex        sync.RWMutex
+	roles        RolesOf[R, P]
+	parents      map[R]map[R]struct{}
+	implications *Implications[P]
+	decisions    *decisionHook[R, P]
+	// instrumentation observes checks, nil when disabled
+	instrumentation Instrumentation
 }
 
+// StdRBAC is the default RBAC implementation where role IDs and permission
+// IDs share the type T.
+type StdRBAC[T comparable] = StdRBACOf[T, T]
+
 // New returns a StdRBAC structure.
 // The default role structure will be used.
 func New[T comparable]() *StdRBAC[T] {
-	return &StdRBAC[T]{
-		roles:   make(Roles[T]),
-		parents: make(map[T]map[T]struct{}),
+	return NewOf[T, T]()
+}
+
+// NewOf returns a StdRBACOf structure with distinct role and permission ID
+// types, e.g. `NewOf[int64, string]()`.
+func NewOf[R, P comparable]() *StdRBACOf[R, P] {
+	return &StdRBACOf[R, P]{
+		roles:   make(RolesOf[R, P]),
+		parents: make(map[R]map[R]struct{}),
 	}
 }
 
+// SetImplications binds a permission implication registry.
+// IsGranted treats a permission as granted when a role holds a stronger one.
+// Passing nil disables implications.
+func (rbac *StdRBACOf[R, P]) SetImplications(imp *Implications[P]) {
+	rbac.mutex.Lock()
+	rbac.implications = imp
+	rbac.mutex.Unlock()
+}
+
+// Implications returns the bound implication registry, or nil.
+func (rbac *StdRBACOf[R, P]) Implications() *Implications[P] {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	return rbac.implications
+}
+
+// Replace swaps in the roles and parents of `other` at once, so that
+// concurrent checks see either the old role model or the new one. The
+// settings of rbac, such as its implications and decision logger, are
+// kept. `other` is left empty.
+func (rbac *StdRBACOf[R, P]) Replace(other *StdRBACOf[R, P]) {
+	other.mutex.Lock()
+	roles, parents := other.roles, other.parents
+	other.roles, other.parents = make(RolesOf[R, P]), make(map[R]map[R]struct{})
+	other.mutex.Unlock()
+	rbac.mutex.Lock()
+	rbac.roles, rbac.parents = roles, parents
+	rbac.mutex.Unlock()
+}
+
 // SetParents bind `parents` to the role `id`.
 // If the role or any of parents is not existing,
 // an error will be returned.
-func (rbac *StdRBAC[T]) SetParents(_ context.Context, id T, parents ...T) error {
+func (rbac *StdRBACOf[R, P]) SetParents(_ context.Context, id R, parents ...R) error {
 	rbac.mutex.Lock()
 	defer rbac.mutex.Unlock()
 	if _, ok := rbac.roles[id]; !ok {
@@ -73,7 +125,7 @@ func (rbac *StdRBAC[T]) SetParents(_ context.Context, id T, parents ...T) error
 		}
 	}
 	if _, ok := rbac.parents[id]; !ok {
-		rbac.parents[id] = make(map[T]struct{})
+		rbac.parents[id] = make(map[R]struct{})
 	}
 	for _, parent := range parents {
 		rbac.parents[id][parent] = empty
@@ -85,7 +137,7 @@ func (rbac *StdRBAC[T]) SetParents(_ context.Context, id T, parents ...T) error
 // If the role is not existing, an error will be returned.
 // Or the role doesn't have any parents,
 // a nil slice will be returned.
-func (rbac *StdRBAC[T]) GetParents(_ context.Context, id T) ([]T, error) {
+func (rbac *StdRBACOf[R, P]) GetParents(_ context.Context, id R) ([]R, error) {
 	rbac.mutex.Lock()
 	defer rbac.mutex.Unlock()
 	if _, ok := rbac.roles[id]; !ok {
@@ -95,7 +147,7 @@ func (rbac *StdRBAC[T]) GetParents(_ context.Context, id T) ([]T, error) {
 	if !ok {
 		return nil, nil
 	}
-	var parents []T
+	var parents []R
 	for parent := range ids {
 		parents = append(parents, parent)
 	}
@@ -105,7 +157,7 @@ func (rbac *StdRBAC[T]) GetParents(_ context.Context, id T) ([]T, error) {
 // RemoveParents unbind `parents` from the role `id`.
 // If the role or any parent is not existing,
 // an error will be returned.
-func (rbac *StdRBAC[T]) RemoveParents(_ context.Context, id T, parents ...T) error {
+func (rbac *StdRBACOf[R, P]) RemoveParents(_ context.Context, id R, parents ...R) error {
 	rbac.mutex.Lock()
 	defer rbac.mutex.Unlock()
 	if _, ok := rbac.roles[id]; !ok {
@@ -123,7 +175,7 @@ func (rbac *StdRBAC[T]) RemoveParents(_ context.Context, id T, parents ...T) err
 }
 
 // Add a role `r`.
-func (rbac *StdRBAC[T]) Add(ctx context.Context, r Role[T]) (err error) {
+func (rbac *StdRBACOf[R, P]) Add(ctx context.Context, r RoleOf[R, P]) (err error) {
 	rbac.mutex.Lock()
 	id := r.ID()
 	if _, ok := rbac.roles[id]; !ok {
@@ -136,7 +188,7 @@ func (rbac *StdRBAC[T]) Add(ctx context.Context, r Role[T]) (err error) {
 }
 
 // Remove the role by `id`.
-func (rbac *StdRBAC[T]) Remove(_ context.Context, id T) (err error) {
+func (rbac *StdRBACOf[R, P]) Remove(_ context.Context, id R) (err error) {
 	rbac.mutex.Lock()
 	if _, ok := rbac.roles[id]; ok {
 		delete(rbac.roles, id)
@@ -160,7 +212,7 @@ func (rbac *StdRBAC[T]) Remove(_ context.Context, id T) (err error) {
 }
 
 // Get returns the role by `id`.
-func (rbac *StdRBAC[T]) Get(_ context.Context, id T) (r Role[T], err error) {
+func (rbac *StdRBACOf[R, P]) Get(_ context.Context, id R) (r RoleOf[R, P], err error) {
 	rbac.mutex.RLock()
 	var ok bool
 	if r, ok = rbac.roles[id]; !ok {
@@ -171,9 +223,9 @@ func (rbac *StdRBAC[T]) Get(_ context.Context, id T) (r Role[T], err error) {
 }
 
 // RoleIDs returns all role IDs.
-func (rbac *StdRBAC[T]) RoleIDs(_ context.Context) []T {
+func (rbac *StdRBACOf[R, P]) RoleIDs(_ context.Context) []R {
 	rbac.mutex.RLock()
-	ids := make([]T, 0, len(rbac.roles))
+	ids := make([]R, 0, len(rbac.roles))
 	for id := range rbac.roles {
 		ids = append(ids, id)
 	}
@@ -182,26 +234,65 @@ func (rbac *StdRBAC[T]) RoleIDs(_ context.Context) []T {
 }
 
 // IsGranted tests if the role `id` has permission `p`.
-func (rbac *StdRBAC[T]) IsGranted(ctx context.Context, id T, p Permission[T]) (ok bool) {
+func (rbac *StdRBACOf[R, P]) IsGranted(ctx context.Context, id R, p Permission[P]) (ok bool) {
+	ok, hook := rbac.observedCheck(ctx, id, p)
+	hook.log(ctx, OpIsGranted, []R{id}, []Permission[P]{p}, ok)
+	return
+}
+
+// observedCheck tests the permission under the read lock and reports the
+// check to the instrumentation. The decision hook is returned for logging
+// outside the lock.
+func (rbac *StdRBACOf[R, P]) observedCheck(ctx context.Context, id R, p Permission[P]) (bool, *decisionHook[R, P]) {
 	rbac.mutex.RLock()
-	ok = rbac.isGranted(ctx, id, p)
+	inst := rbac.instrumentation
+	var start time.Time
+	if inst != nil {
+		start = time.Now()
+	}
+	ok, depth := rbac.isGranted(ctx, id, p)
+	hook := rbac.decisions
 	rbac.mutex.RUnlock()
-	return
+	if inst != nil {
+		inst.ObserveCheck(ctx, CheckEvent{
+			Granted:  ok,
+			Duration: time.Since(start),
+			Depth:    depth,
+		})
+	}
+	return ok, hook
 }
 
-func (rbac *StdRBAC[T]) isGranted(ctx context.Context, id T, p Permission[T]) bool {
-	return rbac.recursionCheck(ctx, id, p)
+// Check tests if the role `id` has permission `p`, like IsGranted, but
+// reports an error when `ctx` is already cancelled or past its deadline.
+// A missing role is not an error; it is simply not granted.
+//
+// A type embedding *StdRBACOf and overriding IsGranted must override Check
+// too, see Checker.
+func (rbac *StdRBACOf[R, P]) Check(ctx context.Context, id R, p Permission[P]) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return rbac.IsGranted(ctx, id, p), nil
+}
+
+// isGranted also returns the deepest hierarchy level walked, 1 being the role itself.
+func (rbac *StdRBACOf[R, P]) isGranted(ctx context.Context, id R, p Permission[P]) (bool, int) {
+	depth := 0
+	ok := rbac.recursionCheck(ctx, id, p, 1, &depth)
+	return ok, depth
 }
 
-func (rbac *StdRBAC[T]) recursionCheck(ctx context.Context, id T, p Permission[T]) bool {
+func (rbac *StdRBACOf[R, P]) recursionCheck(ctx context.Context, id R, p Permission[P], level int, depth *int) bool {
 	if role, ok := rbac.roles[id]; ok {
-		if role.Permit(ctx, p) {
+		*depth = max(*depth, level)
+		if role.Permit(ctx, p) || permitImplied(ctx, rbac.implications, role, p) {
 			return true
 		}
 		if parents, ok := rbac.parents[id]; ok {
 			for pID := range parents {
 				if _, ok := rbac.roles[pID]; ok {
-					if rbac.recursionCheck(ctx, pID, p) {
+					if rbac.recursionCheck(ctx, pID, p, level+1, depth) {
 						return true
 					}
 				}
@@ -210,3 +301,78 @@ func (rbac *StdRBAC[T]) recursionCheck(ctx context.Context, id T, p Permission[T
 	}
 	return false
 }
+
+// BatchGranted tests every permission against the role set `roles` at once.
+// The i-th result reports whether any role grants `permissions[i]`.
+//
+// The inheritance closure of `roles` is collected once and reused for every
+// permission. The batch is observed as one check and logged as one
+// OpBatchGranted decision.
+//
+// A type embedding *StdRBACOf and overriding IsGranted must override
+// BatchGranted too, see BatchGranter.
+func (rbac *StdRBACOf[R, P]) BatchGranted(ctx context.Context, roles []R, permissions ...Permission[P]) []bool {
+	result := make([]bool, len(permissions))
+	rbac.mutex.RLock()
+	inst, hook := rbac.instrumentation, rbac.decisions
+	var start time.Time
+	if inst != nil {
+		start = time.Now()
+	}
+	closure, depth := rbac.closure(roles)
+	for i, p := range permissions {
+		if p == nil {
+			continue
+		}
+		for _, role := range closure {
+			if role.Permit(ctx, p) || permitImplied(ctx, rbac.implications, role, p) {
+				result[i] = true
+				break
+			}
+		}
+	}
+	rbac.mutex.RUnlock()
+	if inst != nil {
+		inst.ObserveCheck(ctx, CheckEvent{
+			Granted:  !slices.Contains(result, false),
+			Duration: time.Since(start),
+			Depth:    depth,
+		})
+	}
+	hook.logBatch(ctx, roles, permissions, result)
+	return result
+}
+
+// closure returns the existing roles in `roles` and all their ancestors,
+// and the deepest inheritance level walked, 1 being the roles themselves.
+func (rbac *StdRBACOf[R, P]) closure(roles []R) ([]RoleOf[R, P], int) {
+	type entry struct {
+		id    R
+		level int
+	}
+	seen := make(map[R]struct{}, len(roles))
+	var result []RoleOf[R, P]
+	depth := 0
+	stack := make([]entry, 0, len(roles))
+	for _, id := range roles {
+		stack = append(stack, entry{id, 1})
+	}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := seen[e.id]; ok {
+			continue
+		}
+		seen[e.id] = empty
+		role, ok := rbac.roles[e.id]
+		if !ok {
+			continue
+		}
+		result = append(result, role)
+		depth = max(depth, e.level)
+		for pID := range rbac.parents[e.id] {
+			stack = append(stack, entry{pID, e.level + 1})
+		}
+	}
+	return result, depth
+}
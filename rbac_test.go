@@ -205,3 +205,56 @@ func BenchmarkRbacNotGranted(b *testing.B) {
 		rbac.IsGranted(ctx, "role-a", pB)
 	}
 }
+
+func TestRbacDistinctIDTypes(t *testing.T) {
+	ctx := context.Background()
+	rbac := NewOf[int64, string]()
+	admin := NewRoleOf[int64, string](1)
+	user := NewRoleOf[int64, string](2)
+	read := NewPermission("read")
+	write := NewPermission("write")
+	assert(t, admin.Assign(ctx, write))
+	assert(t, user.Assign(ctx, read))
+	assert(t, rbac.Add(ctx, admin))
+	assert(t, rbac.Add(ctx, user))
+	assert(t, rbac.SetParents(ctx, 1, 2))
+	if !rbac.IsGranted(ctx, 1, read) {
+		t.Fatal("[1] should have [read] which inherits from [2]")
+	}
+	if rbac.IsGranted(ctx, 2, write) {
+		t.Fatal("[2] should not have [write]")
+	}
+	if !AllGranted(ctx, rbac, []int64{1}, read, write) {
+		t.Fatal("[1] should have [read] and [write]")
+	}
+	if err := InherCircle(ctx, rbac); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRbacReplace(t *testing.T) {
+	ctx := context.Background()
+	rbac := New[string]()
+	imp := NewImplications[string]()
+	assert(t, imp.SetImplies(ctx, "permission-a", "permission-b"))
+	rbac.SetImplications(imp)
+	assert(t, rbac.Add(ctx, NewRole("old")))
+
+	next := New[string]()
+	rA, rB := NewRole("role-a"), NewRole("role-b")
+	assert(t, rA.Assign(ctx, pA))
+	assert(t, next.Add(ctx, rA))
+	assert(t, next.Add(ctx, rB))
+	assert(t, next.SetParents(ctx, "role-b", "role-a"))
+	rbac.Replace(next)
+
+	if _, err := rbac.Get(ctx, "old"); err != ErrRoleNotExist {
+		t.Fatalf("%s expected, but %v got", ErrRoleNotExist, err)
+	}
+	if !rbac.IsGranted(ctx, "role-b", pB) {
+		t.Fatal("role-b should inherit permission-a, which implies permission-b")
+	}
+	if len(next.RoleIDs(ctx)) != 0 {
+		t.Fatal("the replacing RBAC should be left empty")
+	}
+}
@@ -0,0 +1,356 @@
+package rbachttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+// RoleJSON is the JSON representation of a role served by the admin handler.
+type RoleJSON struct {
+	ID          string                            `json:"id"`
+	Permissions []gorbac.PermissionRecord[string] `json:"permissions"`
+	Parents     []string                          `json:"parents"`
+}
+
+type adminConfig struct {
+	newRole func(id string) gorbac.Role[string]
+	opts    []Option[string, string]
+}
+
+// AdminOption customizes NewAdminHandler.
+type AdminOption func(*adminConfig)
+
+// WithRoleFactory replaces gorbac.NewRole for roles created through the API.
+func WithRoleFactory(newRole func(id string) gorbac.Role[string]) AdminOption {
+	return func(cfg *adminConfig) {
+		cfg.newRole = newRole
+	}
+}
+
+// WithAdminMiddlewareOptions forwards options to the Middleware protecting
+// the admin handler, e.g. WithUnauthorized.
+func WithAdminMiddlewareOptions(opts ...Option[string, string]) AdminOption {
+	return func(cfg *adminConfig) {
+		cfg.opts = append(cfg.opts, opts...)
+	}
+}
+
+type adminHandler struct {
+	rbac    gorbac.RBAC[string]
+	newRole func(id string) gorbac.Role[string]
+	// parents serializes the cycle check and the parent change it allows
+	parents sync.Mutex
+}
+
+// NewAdminHandler returns a JSON API managing the roles of `rbac`. Every
+// request requires the `admin` permission for the roles returned by `roles`.
+//
+// Endpoints, relative to where the handler is mounted (see http.StripPrefix):
+//
+//	GET    /roles                          list roles
+//	POST   /roles                          create a role from a RoleJSON body
+//	GET    /roles/{id}                     get a role
+//	DELETE /roles/{id}                     delete a role
+//	POST   /roles/{id}/permissions         assign a JSON array of permissions
+//	DELETE /roles/{id}/permissions/{perm}  revoke a permission
+//	POST   /roles/{id}/parents             add a JSON array of parent IDs
+//	DELETE /roles/{id}/parents/{parent}    remove a parent
+//	GET    /roles/{id}/effective           permissions including inherited ones
+//
+// Permissions use the gorbac.PermissionRecord form. When `rbac` has an
+// `Invalidate(...string)` method, such as gorbac.CachedRBAC, it is called after
+// permissions are assigned or revoked. Parents which would create an
+// inheritance cycle are never set; the request is answered with 409 Conflict.
+func NewAdminHandler(rbac gorbac.RBAC[string], roles RoleExtractor[string], admin gorbac.Permission[string],
+	opts ...AdminOption) http.Handler {
+	cfg := &adminConfig{
+		newRole: func(id string) gorbac.Role[string] { return gorbac.NewRole(id) },
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	h := &adminHandler{rbac: rbac, newRole: cfg.newRole}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /roles", h.list)
+	mux.HandleFunc("POST /roles", h.create)
+	mux.HandleFunc("GET /roles/{id}", h.get)
+	mux.HandleFunc("DELETE /roles/{id}", h.delete)
+	mux.HandleFunc("POST /roles/{id}/permissions", h.assign)
+	mux.HandleFunc("DELETE /roles/{id}/permissions/{perm...}", h.revoke)
+	mux.HandleFunc("POST /roles/{id}/parents", h.setParents)
+	mux.HandleFunc("DELETE /roles/{id}/parents/{parent}", h.removeParent)
+	mux.HandleFunc("GET /roles/{id}/effective", h.effective)
+
+	mw := New(rbac, roles, cfg.opts...)
+	mw.Handle("/", admin)
+	return mw.Wrap(mux)
+}
+
+func (h *adminHandler) list(w http.ResponseWriter, r *http.Request) {
+	ids := h.rbac.RoleIDs(r.Context())
+	sort.Strings(ids)
+	result := make([]RoleJSON, 0, len(ids))
+	for _, id := range ids {
+		role, err := h.roleJSON(r, id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		result = append(result, role)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *adminHandler) create(w http.ResponseWriter, r *http.Request) {
+	var body RoleJSON
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ID == "" {
+		writeJSON(w, http.StatusBadRequest, errorJSON{"a role id is required"})
+		return
+	}
+	perms, err := decodeRecords(body.Permissions)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{err.Error()})
+		return
+	}
+	ctx := r.Context()
+	role := h.newRole(body.ID)
+	if err := role.Assign(ctx, perms...); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.parents.Lock()
+	err = h.checkParents(ctx, body.ID, body.Parents)
+	if err == nil {
+		err = h.rbac.Add(ctx, role)
+	}
+	if err == nil && len(body.Parents) > 0 {
+		if err = h.rbac.SetParents(ctx, body.ID, body.Parents...); err != nil {
+			_ = h.rbac.Remove(ctx, body.ID)
+		}
+	}
+	h.parents.Unlock()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.respondRole(w, r, body.ID, http.StatusCreated)
+}
+
+func (h *adminHandler) get(w http.ResponseWriter, r *http.Request) {
+	h.respondRole(w, r, r.PathValue("id"), http.StatusOK)
+}
+
+func (h *adminHandler) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.rbac.Remove(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *adminHandler) assign(w http.ResponseWriter, r *http.Request) {
+	var records []gorbac.PermissionRecord[string]
+	if err := json.NewDecoder(r.Body).Decode(&records); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{err.Error()})
+		return
+	}
+	perms, err := decodeRecords(records)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{err.Error()})
+		return
+	}
+	id := r.PathValue("id")
+	role, err := h.rbac.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := role.Assign(r.Context(), perms...); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.invalidate(id)
+	h.respondRole(w, r, id, http.StatusOK)
+}
+
+func (h *adminHandler) revoke(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	role, err := h.rbac.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	p, ok := role.Get(r.Context(), r.PathValue("perm"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorJSON{"permission is not assigned"})
+		return
+	}
+	if err := role.Revoke(r.Context(), p); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.invalidate(id)
+	h.respondRole(w, r, id, http.StatusOK)
+}
+
+func (h *adminHandler) setParents(w http.ResponseWriter, r *http.Request) {
+	var parents []string
+	if err := json.NewDecoder(r.Body).Decode(&parents); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{err.Error()})
+		return
+	}
+	ctx, id := r.Context(), r.PathValue("id")
+	h.parents.Lock()
+	err := h.checkParents(ctx, id, parents)
+	if err == nil {
+		err = h.rbac.SetParents(ctx, id, parents...)
+	}
+	h.parents.Unlock()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.respondRole(w, r, id, http.StatusOK)
+}
+
+// checkParents returns gorbac.ErrFoundCircle when `id` is one of `parents`
+// or one of their ancestors: binding them would create an inheritance cycle,
+// on which checks recurse until the stack overflows. Missing roles are left
+// to SetParents to report.
+func (h *adminHandler) checkParents(ctx context.Context, id string, parents []string) error {
+	seen := make(map[string]struct{})
+	queue := append([]string(nil), parents...)
+	for len(queue) > 0 {
+		rid := queue[0]
+		queue = queue[1:]
+		if rid == id {
+			return gorbac.ErrFoundCircle
+		}
+		if _, ok := seen[rid]; ok {
+			continue
+		}
+		seen[rid] = struct{}{}
+		grand, err := h.rbac.GetParents(ctx, rid)
+		if err != nil && !errors.Is(err, gorbac.ErrRoleNotExist) {
+			return err
+		}
+		queue = append(queue, grand...)
+	}
+	return nil
+}
+
+func (h *adminHandler) removeParent(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.rbac.RemoveParents(r.Context(), id, r.PathValue("parent")); err != nil {
+		writeError(w, err)
+		return
+	}
+	h.respondRole(w, r, id, http.StatusOK)
+}
+
+func (h *adminHandler) effective(w http.ResponseWriter, r *http.Request) {
+	perms, err := gorbac.EffectivePermissions(r.Context(), h.rbac, r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	records, err := encodeRecords(perms)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+func (h *adminHandler) respondRole(w http.ResponseWriter, r *http.Request, id string, status int) {
+	role, err := h.roleJSON(r, id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, status, role)
+}
+
+func (h *adminHandler) roleJSON(r *http.Request, id string) (RoleJSON, error) {
+	ctx := r.Context()
+	role, err := h.rbac.Get(ctx, id)
+	if err != nil {
+		return RoleJSON{}, err
+	}
+	parents, err := h.rbac.GetParents(ctx, id)
+	if err != nil {
+		return RoleJSON{}, err
+	}
+	records, err := encodeRecords(role.Permissions(ctx))
+	if err != nil {
+		return RoleJSON{}, err
+	}
+	sort.Strings(parents)
+	if parents == nil {
+		parents = []string{}
+	}
+	return RoleJSON{ID: id, Permissions: records, Parents: parents}, nil
+}
+
+func (h *adminHandler) invalidate(id string) {
+	if c, ok := h.rbac.(interface{ Invalidate(...string) }); ok {
+		c.Invalidate(id)
+	}
+}
+
+func decodeRecords(records []gorbac.PermissionRecord[string]) ([]gorbac.Permission[string], error) {
+	perms := make([]gorbac.Permission[string], 0, len(records))
+	for _, record := range records {
+		p, err := record.Permission()
+		if err != nil {
+			return nil, err
+		}
+		perms = append(perms, p)
+	}
+	return perms, nil
+}
+
+func encodeRecords(perms []gorbac.Permission[string]) ([]gorbac.PermissionRecord[string], error) {
+	records := make([]gorbac.PermissionRecord[string], 0, len(perms))
+	for _, p := range perms {
+		record, err := gorbac.RecordOf(p)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records, nil
+}
+
+type errorJSON struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, gorbac.ErrRoleNotExist):
+		status = http.StatusNotFound
+	case errors.Is(err, gorbac.ErrRoleExist), errors.Is(err, gorbac.ErrFoundCircle):
+		status = http.StatusConflict
+	case errors.Is(err, gorbac.ErrUnsupportedPermission):
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, errorJSON{err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
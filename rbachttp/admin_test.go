@@ -0,0 +1,132 @@
+package rbachttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fy0/gorbac/v3"
+	"github.com/fy0/gorbac/v3/rbachttp"
+)
+
+func TestAdminHandler(t *testing.T) {
+	ctx := context.Background()
+	rbac := gorbac.New[string]()
+	root := gorbac.NewRole("root")
+	must(t, root.Assign(ctx, gorbac.NewPermission("rbac:admin")))
+	must(t, rbac.Add(ctx, root))
+	cached := gorbac.NewCached[string, string](rbac)
+	handler := rbachttp.NewAdminHandler(cached, headerRoles, gorbac.NewPermission("rbac:admin"))
+
+	do := func(method, path, roles, body string, status int) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Roles", roles)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != status {
+			t.Fatalf("%s %s: %d expected, but %d got: %s", method, path, status, rec.Code, rec.Body)
+		}
+		return rec
+	}
+
+	do(http.MethodGet, "/roles", "guest", "", http.StatusForbidden)
+	do(http.MethodPost, "/roles", "root", `{"id":"reader","permissions":["read"]}`, http.StatusCreated)
+	do(http.MethodPost, "/roles", "root", `{"id":"reader"}`, http.StatusConflict)
+	do(http.MethodPost, "/roles", "root", `{"id":"editor","parents":["missing"]}`, http.StatusNotFound)
+	do(http.MethodPost, "/roles", "root", `{"id":"editor","parents":["reader"],"permissions":[{"id":"doc::write","sep":"::"}]}`, http.StatusCreated)
+
+	if !cached.IsGranted(ctx, "editor", gorbac.NewPermission("read")) {
+		t.Fatal("editor should inherit read")
+	}
+	do(http.MethodPost, "/roles/reader/permissions", "root", `[{"id":"project:read","filter":"creator_id == 1"}]`, http.StatusOK)
+	do(http.MethodDelete, "/roles/reader/permissions/read", "root", "", http.StatusOK)
+	do(http.MethodDelete, "/roles/reader/permissions/read", "root", "", http.StatusNotFound)
+	if cached.IsGranted(ctx, "editor", gorbac.NewPermission("read")) {
+		t.Fatal("the cached decision should be invalidated after revoking")
+	}
+
+	var effective []gorbac.PermissionRecord[string]
+	rec := do(http.MethodGet, "/roles/editor/effective", "root", "", http.StatusOK)
+	must(t, json.Unmarshal(rec.Body.Bytes(), &effective))
+	if len(effective) != 2 || effective[0].ID != "doc::write" || effective[1].Filter != "creator_id == 1" {
+		t.Fatalf("unexpected effective permissions %+v", effective)
+	}
+
+	do(http.MethodDelete, "/roles/editor/parents/reader", "root", "", http.StatusOK)
+	do(http.MethodPost, "/roles/editor/parents", "root", `["reader"]`, http.StatusOK)
+	do(http.MethodPost, "/roles/reader/parents", "root", `["editor"]`, http.StatusConflict)
+	do(http.MethodPost, "/roles", "root", `{"id":"loop","parents":["loop"]}`, http.StatusConflict)
+	do(http.MethodGet, "/roles/loop", "root", "", http.StatusNotFound)
+	if parents, err := cached.GetParents(ctx, "reader"); err != nil || len(parents) != 0 {
+		t.Fatalf("the cycle should be rejected, got %v, %v", parents, err)
+	}
+	if cached.IsGranted(ctx, "reader", gorbac.NewPermission("doc::write")) {
+		t.Fatal("reader should not inherit from editor")
+	}
+	do(http.MethodDelete, "/roles/reader", "root", "", http.StatusNoContent)
+	var role rbachttp.RoleJSON
+	rec = do(http.MethodGet, "/roles/editor", "root", "", http.StatusOK)
+	must(t, json.Unmarshal(rec.Body.Bytes(), &role))
+	if len(role.Parents) != 0 || len(role.Permissions) != 1 {
+		t.Fatalf("unexpected role %+v", role)
+	}
+	var roles []rbachttp.RoleJSON
+	rec = do(http.MethodGet, "/roles", "root", "", http.StatusOK)
+	must(t, json.Unmarshal(rec.Body.Bytes(), &roles))
+	if len(roles) != 2 || roles[0].ID != "editor" || roles[1].ID != "root" {
+		t.Fatalf("unexpected roles %+v", roles)
+	}
+	do(http.MethodGet, "/roles/missing", "root", "", http.StatusNotFound)
+}
+
+// parentsSpy counts the SetParents calls reaching the RBAC.
+type parentsSpy struct {
+	gorbac.RBAC[string]
+	calls int
+}
+
+func (s *parentsSpy) SetParents(ctx context.Context, id string, parents ...string) error {
+	s.calls++
+	return s.RBAC.SetParents(ctx, id, parents...)
+}
+
+func TestAdminHandlerRejectsCycles(t *testing.T) {
+	ctx := context.Background()
+	rbac := gorbac.New[string]()
+	root := gorbac.NewRole("root")
+	must(t, root.Assign(ctx, gorbac.NewPermission("rbac:admin")))
+	for _, role := range []gorbac.Role[string]{root, gorbac.NewRole("a"), gorbac.NewRole("b"), gorbac.NewRole("c")} {
+		must(t, rbac.Add(ctx, role))
+	}
+	must(t, rbac.SetParents(ctx, "b", "a"))
+	must(t, rbac.SetParents(ctx, "c", "b"))
+	spy := &parentsSpy{RBAC: rbac}
+	handler := rbachttp.NewAdminHandler(spy, headerRoles, gorbac.NewPermission("rbac:admin"))
+
+	for _, tt := range []struct{ path, body string }{
+		{"/roles/a/parents", `["c"]`},
+		{"/roles/a/parents", `["root", "a"]`},
+		{"/roles", `{"id":"d","parents":["d"]}`},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("X-Roles", "root")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("%s %s: 409 expected, but %d got", tt.path, tt.body, rec.Code)
+		}
+	}
+	if spy.calls != 0 {
+		t.Fatalf("a cyclic edge should never be set, got %d SetParents calls", spy.calls)
+	}
+	if err := gorbac.InherCircle(ctx, rbac); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rbac.Get(ctx, "d"); err == nil {
+		t.Fatal("a role with a cyclic parent should not be created")
+	}
+}
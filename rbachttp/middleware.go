@@ -0,0 +1,215 @@
+// Package rbachttp provides net/http integration for gorbac.
+//
+// A Middleware maps request patterns (the same syntax as http.ServeMux, e.g.
+// "GET /projects/{id}") to required permissions, extracts the caller's roles
+// from the request, and answers 401/403 before the wrapped handler runs:
+//
+//	mw := rbachttp.New(rbac, rolesFromSession)
+//	mw.Handle("GET /projects/", readProject)
+//	mw.Handle("DELETE /projects/{id}", deleteProject)
+//	http.ListenAndServe(":8080", mw.Wrap(mux))
+//
+// NewAdminHandler serves a JSON API for managing roles, protected by the
+// same Middleware.
+package rbachttp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fy0/gorbac/v3"
+	"github.com/fy0/gorbac/v3/filter"
+)
+
+// RoleExtractor returns the role IDs of the caller.
+// An error means the caller is not authenticated and results in 401.
+//
+// The roles are attached to the request context with gorbac.WithRoles, so
+// handlers can call gorbac.GrantedFromContext deeper in the call stack.
+type RoleExtractor[R comparable] func(r *http.Request) ([]R, error)
+
+type route[P comparable] struct {
+	permissions []gorbac.Permission[P]
+	all         bool
+}
+
+// Middleware authorizes requests against an RBAC instance.
+type Middleware[R, P comparable] struct {
+	rbac  gorbac.RBACOf[R, P]
+	roles RoleExtractor[R]
+
+	mux    *http.ServeMux
+	routes map[string]route[P]
+
+	allowUnmatched bool
+	unauthorized   http.Handler
+	forbidden      http.Handler
+	onError        func(http.ResponseWriter, *http.Request, error)
+	scope          *filter.Engine
+}
+
+// Option customizes Middleware construction.
+type Option[R, P comparable] func(*Middleware[R, P])
+
+// WithUnauthorized replaces the default 401 response.
+func WithUnauthorized[R, P comparable](h http.Handler) Option[R, P] {
+	return func(m *Middleware[R, P]) {
+		m.unauthorized = h
+	}
+}
+
+// WithForbidden replaces the default 403 response.
+func WithForbidden[R, P comparable](h http.Handler) Option[R, P] {
+	return func(m *Middleware[R, P]) {
+		m.forbidden = h
+	}
+}
+
+// WithErrorHandler replaces the default 500 response written when the RBAC
+// check or the data scope compilation fails.
+func WithErrorHandler[R, P comparable](h func(http.ResponseWriter, *http.Request, error)) Option[R, P] {
+	return func(m *Middleware[R, P]) {
+		m.onError = h
+	}
+}
+
+// WithAllowUnmatched lets requests matching no registered pattern through,
+// including anonymous ones: their roles are attached to the context only
+// when they can be extracted. By default they are forbidden.
+func WithAllowUnmatched[R, P comparable]() Option[R, P] {
+	return func(m *Middleware[R, P]) {
+		m.allowUnmatched = true
+	}
+}
+
+// WithDataScope compiles the data-scope filter of the caller for every
+// authorized request and stores it in the request context, see
+// ProgramFromContext. The route's permissions select the filter permissions,
+// as in gorbac.FilterExprsForRoles.
+//
+// `engine`, built with filter.NewEngine, compiles the filters of every
+// request.
+func WithDataScope[R, P comparable](engine *filter.Engine) Option[R, P] {
+	return func(m *Middleware[R, P]) {
+		m.scope = engine
+	}
+}
+
+// New returns a Middleware checking requests against `rbac` with the roles
+// returned by `roles`.
+func New[R, P comparable](rbac gorbac.RBACOf[R, P], roles RoleExtractor[R], opts ...Option[R, P]) *Middleware[R, P] {
+	m := &Middleware[R, P]{
+		rbac:   rbac,
+		roles:  roles,
+		mux:    http.NewServeMux(),
+		routes: make(map[string]route[P]),
+		unauthorized: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		}),
+		forbidden: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		}),
+		onError: func(w http.ResponseWriter, _ *http.Request, _ error) {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		},
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(m)
+	}
+	return m
+}
+
+// Handle requires any of `permissions` for requests matching `pattern`.
+// Patterns follow http.ServeMux; like http.ServeMux.Handle, Handle panics on
+// invalid or conflicting patterns.
+//
+// A pattern registered without permissions only requires authentication:
+// any caller whose roles are extracted is let through.
+func (m *Middleware[R, P]) Handle(pattern string, permissions ...gorbac.Permission[P]) *Middleware[R, P] {
+	return m.handle(pattern, permissions, false)
+}
+
+// HandleAll requires all of `permissions` for requests matching `pattern`.
+func (m *Middleware[R, P]) HandleAll(pattern string, permissions ...gorbac.Permission[P]) *Middleware[R, P] {
+	return m.handle(pattern, permissions, true)
+}
+
+func (m *Middleware[R, P]) handle(pattern string, permissions []gorbac.Permission[P], all bool) *Middleware[R, P] {
+	m.mux.Handle(pattern, http.NotFoundHandler())
+	m.routes[pattern] = route[P]{permissions: permissions, all: all}
+	return m
+}
+
+// Wrap returns a handler which authorizes each request before calling `next`.
+func (m *Middleware[R, P]) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := m.mux.Handler(r)
+		rt, matched := m.routes[pattern]
+		if !matched && !m.allowUnmatched {
+			m.forbidden.ServeHTTP(w, r)
+			return
+		}
+		roles, err := m.roles(r)
+		if !matched {
+			if err == nil {
+				r = r.WithContext(gorbac.WithRoles(r.Context(), roles...))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			m.unauthorized.ServeHTTP(w, r)
+			return
+		}
+		ctx := gorbac.WithRoles(r.Context(), roles...)
+
+		if len(rt.permissions) == 0 {
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		check := gorbac.CheckAny[R, P]
+		if rt.all {
+			check = gorbac.CheckAll[R, P]
+		}
+		ok, err := check(ctx, m.rbac, roles, rt.permissions...)
+		if err != nil {
+			m.onError(w, r, err)
+			return
+		}
+		if !ok {
+			m.forbidden.ServeHTTP(w, r)
+			return
+		}
+
+		if m.scope != nil {
+			program, err := m.compileScope(ctx, roles, rt.permissions)
+			if err != nil {
+				m.onError(w, r, err)
+				return
+			}
+			ctx = context.WithValue(ctx, programKey{}, program)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (m *Middleware[R, P]) compileScope(ctx context.Context, roles []R, permissions []gorbac.Permission[P]) (*filter.Program, error) {
+	exprs, err := gorbac.FilterExprsForRoles(ctx, m.rbac, roles, permissions)
+	if err != nil {
+		return nil, err
+	}
+	return gorbac.FilterProgramFromEngine(ctx, m.scope, exprs)
+}
+
+type programKey struct{}
+
+// ProgramFromContext returns the caller's data-scope program compiled by a
+// Middleware configured WithDataScope.
+func ProgramFromContext(ctx context.Context) (*filter.Program, bool) {
+	program, ok := ctx.Value(programKey{}).(*filter.Program)
+	return program, ok
+}
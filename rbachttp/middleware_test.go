@@ -0,0 +1,160 @@
+package rbachttp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fy0/gorbac/v3"
+	"github.com/fy0/gorbac/v3/filter"
+	"github.com/fy0/gorbac/v3/rbachttp"
+	"github.com/google/cel-go/cel"
+)
+
+func testRBAC(t *testing.T) *gorbac.StdRBAC[string] {
+	t.Helper()
+	ctx := context.Background()
+	rbac := gorbac.New[string]()
+	reader := gorbac.NewRole("reader")
+	editor := gorbac.NewRole("editor")
+	must(t, reader.Assign(ctx, gorbac.NewFilterPermission("project:read", "creator_id == current_user_id")))
+	must(t, editor.Assign(ctx, gorbac.NewPermission("project:delete")))
+	must(t, rbac.Add(ctx, reader))
+	must(t, rbac.Add(ctx, editor))
+	must(t, rbac.SetParents(ctx, "editor", "reader"))
+	return rbac
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func headerRoles(r *http.Request) ([]string, error) {
+	v := r.Header.Get("X-Roles")
+	if v == "" {
+		return nil, errors.New("anonymous")
+	}
+	return strings.Split(v, ","), nil
+}
+
+func testSchema() filter.Schema {
+	return filter.Schema{
+		Name: "project",
+		Fields: map[string]*filter.Field{
+			"creator_id": {
+				Name:   "creator_id",
+				Type:   filter.FieldTypeInt,
+				Column: filter.Column{Table: "project", Name: "creator_id"},
+				AllowedComparisonOps: map[filter.ComparisonOperator]bool{
+					filter.CompareEq: true,
+				},
+			},
+		},
+		EnvOptions: []cel.EnvOption{
+			cel.Variable("creator_id", cel.IntType),
+			cel.Variable("current_user_id", cel.IntType),
+		},
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	engine, err := filter.NewEngine(testSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mw := rbachttp.New(testRBAC(t), headerRoles,
+		rbachttp.WithDataScope[string, string](engine),
+		rbachttp.WithForbidden[string, string](http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})),
+	)
+	mw.Handle("GET /projects/", gorbac.NewPermission("project:read"))
+	mw.Handle("DELETE /projects/{id}", gorbac.NewPermission("project:delete"))
+
+	var sql string
+	handler := mw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sql = ""
+		if program, ok := rbachttp.ProgramFromContext(r.Context()); ok {
+			stmt, err := program.RenderSQL(filter.Bindings{"current_user_id": 7}, filter.RenderOptions{Dialect: filter.DialectMySQL})
+			must(t, err)
+			sql = stmt.SQL
+		}
+		if !gorbac.GrantedFromContext(r.Context(), gorbac.RBAC[string](testRBAC(t)), gorbac.NewPermission("project:read")) {
+			t.Error("roles should be stored in the context")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := []struct {
+		method, path, roles string
+		status              int
+	}{
+		{http.MethodGet, "/projects/1", "reader", http.StatusOK},
+		{http.MethodGet, "/projects/1", "", http.StatusUnauthorized},
+		{http.MethodDelete, "/projects/1", "reader", http.StatusNotFound},
+		{http.MethodDelete, "/projects/1", "editor", http.StatusOK},
+		{http.MethodPost, "/projects/1", "editor", http.StatusNotFound},
+		{http.MethodGet, "/other", "editor", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		if c.roles != "" {
+			req.Header.Set("X-Roles", c.roles)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != c.status {
+			t.Fatalf("%s %s as %q: %d expected, but %d got", c.method, c.path, c.roles, c.status, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/projects/1", nil)
+	req.Header.Set("X-Roles", "reader")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if sql != "`project`.`creator_id` = ?" {
+		t.Fatalf("unexpected data scope SQL %q", sql)
+	}
+}
+
+func TestMiddlewareAllowUnmatched(t *testing.T) {
+	mw := rbachttp.New(testRBAC(t), headerRoles, rbachttp.WithAllowUnmatched[string, string]())
+	mw.HandleAll("/admin/", gorbac.NewPermission("project:read"), gorbac.NewPermission("project:delete"))
+	mw.Handle("/me")
+	var roles []string
+	handler := mw.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		roles, _ = gorbac.RolesFromContext[string](r.Context())
+	}))
+
+	for path, status := range map[string]int{
+		"/health":     http.StatusOK,
+		"/admin/page": http.StatusForbidden,
+		"/me":         http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Roles", "reader")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != status {
+			t.Fatalf("%s: %d expected, but %d got", path, status, rec.Code)
+		}
+	}
+	// a route without permissions still requires authentication
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/me", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("401 expected, but %d got", rec.Code)
+	}
+	// an unmatched route is public
+	roles = []string{"stale"}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK || roles != nil {
+		t.Fatalf("anonymous requests should pass without roles, got %d, %v", rec.Code, roles)
+	}
+}
@@ -0,0 +1,231 @@
+// Package rbaclint analyzes a role model for redundant and dead configuration.
+//
+//	findings, err := rbaclint.Analyze(ctx, rbac, rbaclint.WithSchema(schema))
+//	for _, f := range findings {
+//		fmt.Println(f)
+//	}
+//
+// The rules are:
+//
+//   - cycle (error): the role inherits from itself, see gorbac.InherCircle.
+//   - missing-parent (error): a parent ID which is not a role.
+//   - invalid-filter (error): a filter permission whose CEL expression is
+//     empty or, with WithSchema, does not compile against the schema.
+//   - redundant-permission (warning): a permission the role already inherits.
+//   - empty-role (warning): a role without permissions, parents or children;
+//     info when it has parents, as it may merely aggregate them.
+//   - redundant-parent (info): a parent already inherited through another
+//     parent.
+package rbaclint
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fy0/gorbac/v3"
+	"github.com/fy0/gorbac/v3/filter"
+)
+
+// Severity ranks findings.
+type Severity int
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Rules reported in Finding.Rule.
+const (
+	RuleCycle               = "cycle"
+	RuleMissingParent       = "missing-parent"
+	RuleInvalidFilter       = "invalid-filter"
+	RuleRedundantPermission = "redundant-permission"
+	RuleEmptyRole           = "empty-role"
+	RuleRedundantParent     = "redundant-parent"
+)
+
+// Finding is one problem of the role `Role`.
+type Finding[R comparable] struct {
+	Severity Severity
+	Rule     string
+	Role     R
+	Message  string
+}
+
+func (f Finding[R]) String() string {
+	return fmt.Sprintf("%s: %v: %s (%s)", f.Severity, f.Role, f.Message, f.Rule)
+}
+
+type config struct {
+	schema     *filter.Schema
+	engineOpts []filter.EngineOption
+}
+
+// Option customizes Analyze.
+type Option func(*config)
+
+// WithSchema compiles the filter permissions against `schema`.
+func WithSchema(schema filter.Schema, engineOpts ...filter.EngineOption) Option {
+	return func(cfg *config) {
+		cfg.schema = &schema
+		cfg.engineOpts = engineOpts
+	}
+}
+
+type node[R, P comparable] struct {
+	role     gorbac.RoleOf[R, P]
+	parents  []R
+	children int
+}
+
+// Analyze reports the findings of every rule, most severe first. Errors of
+// `rbac` itself, e.g. from Walk, are returned as is.
+func Analyze[R, P comparable](ctx context.Context, rbac gorbac.RBACOf[R, P], opts ...Option) ([]Finding[R], error) {
+	cfg := &config{}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	nodes := make(map[R]*node[R, P])
+	err := gorbac.Walk(ctx, rbac, func(role gorbac.RoleOf[R, P], parents []R) error {
+		nodes[role.ID()] = &node[R, P]{role: role, parents: parents}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		for _, parent := range n.parents {
+			if p, ok := nodes[parent]; ok {
+				p.children++
+			}
+		}
+	}
+
+	var engine *filter.Engine
+	if cfg.schema != nil {
+		if engine, err = filter.NewEngine(*cfg.schema, cfg.engineOpts...); err != nil {
+			return nil, err
+		}
+	}
+
+	var findings []Finding[R]
+	add := func(sev Severity, rule string, id R, format string, args ...any) {
+		findings = append(findings, Finding[R]{Severity: sev, Rule: rule, Role: id, Message: fmt.Sprintf(format, args...)})
+	}
+	cyclic := gorbac.InherCircle(ctx, rbac) != nil
+
+	for id, n := range nodes {
+		ancestors := ancestorsOf(nodes, n.parents)
+		if _, ok := ancestors[id]; ok && cyclic {
+			add(Error, RuleCycle, id, "role inherits from itself")
+		}
+
+		for _, parent := range n.parents {
+			if _, ok := nodes[parent]; !ok {
+				add(Error, RuleMissingParent, id, "parent %v is not a role", parent)
+				continue
+			}
+			for _, other := range n.parents {
+				if other == parent {
+					continue
+				}
+				if _, ok := ancestorsOf(nodes, []R{other})[parent]; ok {
+					add(Info, RuleRedundantParent, id, "parent %v is already inherited through %v", parent, other)
+					break
+				}
+			}
+		}
+
+		for pid, p := range n.role.FilterPermissions(ctx) {
+			f, ok := p.(interface{ CEL() (string, error) })
+			if !ok {
+				continue
+			}
+			expr, err := f.CEL()
+			if err == nil && strings.TrimSpace(expr) == "" {
+				err = fmt.Errorf("filter expression is empty")
+			}
+			if err == nil && engine != nil {
+				_, err = engine.Compile(expr)
+			}
+			if err != nil {
+				add(Error, RuleInvalidFilter, id, "filter of %v: %v", pid, err)
+			}
+		}
+
+		ordered := make([]R, 0, len(ancestors))
+		for aid := range ancestors {
+			ordered = append(ordered, aid)
+		}
+		slices.SortFunc(ordered, func(a, b R) int { return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b)) })
+		perms := n.role.Permissions(ctx)
+		for _, p := range perms {
+			if _, ok := p.(interface{ CEL() (string, error) }); ok {
+				// filters of the same permission are combined, not redundant
+				continue
+			}
+			for _, aid := range ordered {
+				if a, ok := nodes[aid]; ok && aid != id && a.role.Permit(ctx, p) {
+					add(Warning, RuleRedundantPermission, id, "%v is already inherited from %v", p.ID(), aid)
+					break
+				}
+			}
+		}
+
+		if len(perms) == 0 && n.children == 0 {
+			if len(n.parents) == 0 {
+				add(Warning, RuleEmptyRole, id, "role has no permissions, parents or children")
+			} else {
+				add(Info, RuleEmptyRole, id, "role has no permissions and no children")
+			}
+		}
+	}
+
+	slices.SortFunc(findings, func(a, b Finding[R]) int {
+		return cmp.Or(
+			cmp.Compare(b.Severity, a.Severity),
+			cmp.Compare(fmt.Sprint(a.Role), fmt.Sprint(b.Role)),
+			cmp.Compare(a.Rule, b.Rule),
+			cmp.Compare(a.Message, b.Message),
+		)
+	})
+	return findings, nil
+}
+
+// ancestorsOf returns the roles reachable from `parents`, including them.
+func ancestorsOf[R, P comparable](nodes map[R]*node[R, P], parents []R) map[R]struct{} {
+	seen := make(map[R]struct{})
+	stack := slices.Clone(parents)
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		if n, ok := nodes[id]; ok {
+			stack = append(stack, n.parents...)
+		}
+	}
+	return seen
+}
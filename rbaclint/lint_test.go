@@ -0,0 +1,96 @@
+package rbaclint
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/fy0/gorbac/v3"
+	"github.com/fy0/gorbac/v3/filter"
+)
+
+// ghostParents reports a parent which is not a role.
+type ghostParents struct {
+	gorbac.RBAC[string]
+}
+
+func (g ghostParents) GetParents(ctx context.Context, id string) ([]string, error) {
+	parents, err := g.RBAC.GetParents(ctx, id)
+	if id == "ghostly" {
+		parents = append(parents, "ghost")
+	}
+	return parents, err
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	ctx := context.Background()
+	rbac := gorbac.New[string]()
+	roles := map[string][]gorbac.Permission[string]{
+		"base":    {gorbac.NewPermission("read")},
+		"editor":  {gorbac.NewPermission("read"), gorbac.NewPermission("write"), gorbac.NewFilterPermission("posts", "owner == 1")},
+		"chief":   {gorbac.NewPermission("delete"), gorbac.NewFilterPermission("posts", "unknown == 1")},
+		"orphan":  nil,
+		"alias":   nil,
+		"a":       {gorbac.NewPermission("x")},
+		"b":       {gorbac.NewPermission("y")},
+		"ghostly": {gorbac.NewFilterPermission("blank", " ")},
+	}
+	for id, perms := range roles {
+		role := gorbac.NewRole(id)
+		must(t, role.Assign(ctx, perms...))
+		must(t, rbac.Add(ctx, role))
+	}
+	must(t, rbac.SetParents(ctx, "editor", "base"))
+	must(t, rbac.SetParents(ctx, "chief", "editor", "base"))
+	must(t, rbac.SetParents(ctx, "alias", "chief"))
+	must(t, rbac.SetParents(ctx, "a", "b"))
+	must(t, rbac.SetParents(ctx, "b", "a"))
+
+	schema, err := filter.SchemaFromJSON([]byte(`{"name": "posts", "table": "posts", "fields": {"owner": {"type": "int"}}}`))
+	must(t, err)
+	findings, err := Analyze(ctx, ghostParents{rbac}, WithSchema(schema))
+	must(t, err)
+
+	type key struct {
+		sev  Severity
+		rule string
+		role string
+	}
+	var got []key
+	for _, f := range findings {
+		got = append(got, key{f.Severity, f.Rule, f.Role})
+	}
+	want := []key{
+		{Error, RuleCycle, "a"},
+		{Error, RuleCycle, "b"},
+		{Error, RuleInvalidFilter, "chief"},
+		{Error, RuleInvalidFilter, "ghostly"},
+		{Error, RuleMissingParent, "ghostly"},
+		{Warning, RuleRedundantPermission, "editor"},
+		{Warning, RuleEmptyRole, "orphan"},
+		{Info, RuleEmptyRole, "alias"},
+		{Info, RuleRedundantParent, "chief"},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected findings:\n%v", findings)
+	}
+	if s := findings[5].String(); s != "warning: editor: read is already inherited from base (redundant-permission)" {
+		t.Fatalf("unexpected message %q", s)
+	}
+
+	// without a schema only empty filters are invalid
+	findings, err = Analyze(ctx, rbac)
+	must(t, err)
+	for _, f := range findings {
+		if f.Rule == RuleInvalidFilter && f.Role == "chief" {
+			t.Fatal("filters should not be compiled without a schema")
+		}
+	}
+}
@@ -0,0 +1,340 @@
+// Package rbacrecommend proposes least-privilege changes from decision logs.
+//
+// Granted decisions (see gorbac.SetDecisionLogger and gorbac.ReadDecisions)
+// show which permissions each role actually exercised. Recommend turns the
+// permissions never exercised over a period into a Plan:
+//
+//   - revoke: a directly assigned permission nobody exercised, through the
+//     role itself or any role inheriting it.
+//   - add-role: with WithNarrowRoleID, a narrower role holding only the
+//     exercised permissions of a role whose subjects use a small part of it.
+//     The listed subjects are candidates to move to the new role.
+//
+// For example:
+//
+//	decisions, _ := gorbac.ReadDecisions[string, string](logFile)
+//	plan, _ := rbacrecommend.Recommend(ctx, rbac, decisions,
+//		rbacrecommend.WithPeriod(since, time.Time{}))
+//	fmt.Print(plan)
+//	err := plan.Apply(ctx, rbac)
+//
+// Decisions only record permission IDs, so exercised permissions are matched
+// as gorbac.StdPermission. Permissions whose use cannot be told from the IDs
+// are never proposed for revocation: layered, filter and custom permissions,
+// and permissions implying others through the gorbac.Implications of the
+// RBAC or of the role. Neither are permissions of a denied decision which
+// does not tell which of its permissions were granted, such as a failed
+// gorbac.AllGranted.
+package rbacrecommend
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+// ErrNarrowRoleID occurred if the function given to WithNarrowRoleID does
+// not take and return the role ID type of the RBAC
+var ErrNarrowRoleID = errors.New("Narrow role ID function does not match the role ID type")
+
+// DefaultMaxUsage is the share of effective permissions under which a
+// narrower role is proposed.
+const DefaultMaxUsage = 0.5
+
+type config struct {
+	from, to time.Time
+	narrowID any
+	maxUsage float64
+}
+
+// Option customizes Recommend.
+type Option func(*config)
+
+// WithPeriod only considers decisions made at or after `from` and before
+// `to`. A zero time leaves that side unbounded.
+func WithPeriod(from, to time.Time) Option {
+	return func(cfg *config) {
+		cfg.from, cfg.to = from, to
+	}
+}
+
+// WithNarrowRoleID enables add-role proposals; `id` names the narrower role
+// proposed for a role, e.g. `func(id string) string { return id + "-minimal" }`.
+func WithNarrowRoleID[R comparable](id func(R) R) Option {
+	return func(cfg *config) {
+		cfg.narrowID = id
+	}
+}
+
+// WithMaxUsage proposes a narrower role when the subjects of a role
+// exercised at most `share` of its effective permissions; DefaultMaxUsage
+// by default.
+func WithMaxUsage(share float64) Option {
+	return func(cfg *config) {
+		cfg.maxUsage = share
+	}
+}
+
+// ChangeKind tells what a Change does.
+type ChangeKind string
+
+const (
+	// ChangeRevoke revokes Permissions from Role.
+	ChangeRevoke ChangeKind = "revoke"
+	// ChangeAddRole adds Role holding Permissions, narrowing Base.
+	ChangeAddRole ChangeKind = "add-role"
+)
+
+// Change is one step of a Plan.
+type Change[R, P comparable] struct {
+	Kind        ChangeKind
+	Role        R
+	Permissions []gorbac.Permission[P]
+	// Base is the role narrowed by ChangeAddRole.
+	Base R
+	// Subjects exercised Base; they are candidates to move to Role.
+	Subjects []string
+	Reason   string
+}
+
+func (c Change[R, P]) String() string {
+	ids := make([]string, len(c.Permissions))
+	for i, p := range c.Permissions {
+		ids[i] = fmt.Sprint(p.ID())
+	}
+	s := fmt.Sprintf("%s %v: %s", c.Kind, c.Role, strings.Join(ids, ", "))
+	if c.Reason != "" {
+		s += " (" + c.Reason + ")"
+	}
+	return s
+}
+
+// Plan is an ordered list of changes.
+type Plan[R, P comparable] struct {
+	Changes []Change[R, P]
+}
+
+func (p Plan[R, P]) String() string {
+	var b strings.Builder
+	for _, c := range p.Changes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Apply performs the changes on `rbac`. Roles are created with
+// gorbac.NewRoleOf unless `newRole` is given. When `rbac` has an
+// `Invalidate(...R)` method, such as gorbac.CachedRBAC, it is called for
+// every changed role.
+func (p Plan[R, P]) Apply(ctx context.Context, rbac gorbac.RBACOf[R, P], newRole ...func(R) gorbac.RoleOf[R, P]) error {
+	factory := func(id R) gorbac.RoleOf[R, P] { return gorbac.NewRoleOf[R, P](id) }
+	if len(newRole) > 0 && newRole[0] != nil {
+		factory = newRole[0]
+	}
+	for _, c := range p.Changes {
+		switch c.Kind {
+		case ChangeRevoke:
+			role, err := rbac.Get(ctx, c.Role)
+			if err != nil {
+				return fmt.Errorf("%v: %w", c.Role, err)
+			}
+			if err := role.Revoke(ctx, c.Permissions...); err != nil {
+				return fmt.Errorf("%v: %w", c.Role, err)
+			}
+		case ChangeAddRole:
+			role := factory(c.Role)
+			if err := role.Assign(ctx, c.Permissions...); err != nil {
+				return fmt.Errorf("%v: %w", c.Role, err)
+			}
+			if err := rbac.Add(ctx, role); err != nil {
+				return fmt.Errorf("%v: %w", c.Role, err)
+			}
+		default:
+			return fmt.Errorf("unknown change %q", c.Kind)
+		}
+		if inv, ok := rbac.(interface{ Invalidate(...R) }); ok {
+			inv.Invalidate(c.Role)
+		}
+	}
+	return nil
+}
+
+// Recommend analyzes the granted decisions of the period against `rbac`
+// and returns the proposed changes, add-role changes first.
+func Recommend[R, P comparable](ctx context.Context, rbac gorbac.RBACOf[R, P], decisions []gorbac.Decision[R, P],
+	opts ...Option) (Plan[R, P], error) {
+	cfg := &config{maxUsage: DefaultMaxUsage}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	narrowID, ok := cfg.narrowID.(func(R) R)
+	if cfg.narrowID != nil && !ok {
+		return Plan[R, P]{}, fmt.Errorf("%w: %T", ErrNarrowRoleID, cfg.narrowID)
+	}
+
+	// exercised[holder][permission ID]: a direct permission of holder was used
+	exercised := make(map[R]map[P]struct{})
+	// used[role][permission ID]: permissions requested and granted through role
+	used := make(map[R]map[P]struct{})
+	subjects := make(map[R]map[string]struct{})
+	// uncertain[permission ID]: maybe granted by a denied decision
+	uncertain := make(map[P]struct{})
+	for _, d := range decisions {
+		if !cfg.in(d.Time) {
+			continue
+		}
+		if !d.Granted && d.Results == nil && len(d.Permissions) > 1 {
+			for _, pid := range d.Permissions {
+				uncertain[pid] = struct{}{}
+			}
+			continue
+		}
+		for i, pid := range d.Permissions {
+			// a batch may be granted in part
+			if !d.Granted && (i >= len(d.Results) || !d.Results[i]) {
+				continue
+			}
+			p := gorbac.NewPermission(pid)
+			for _, rid := range d.Roles {
+				path, ok := gorbac.GrantPath(ctx, rbac, rid, p)
+				if !ok {
+					continue
+				}
+				holder, err := rbac.Get(ctx, path[len(path)-1])
+				if err != nil {
+					continue
+				}
+				for _, held := range holder.Permissions(ctx) {
+					if held.Match(p) {
+						mark(exercised, holder.ID(), held.ID())
+					}
+				}
+				mark(used, rid, pid)
+				if d.Subject != "" {
+					mark(subjects, rid, d.Subject)
+				}
+			}
+		}
+	}
+
+	ids := rbac.RoleIDs(ctx)
+	slices.SortFunc(ids, func(a, b R) int { return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b)) })
+	var plan Plan[R, P]
+	var revokes []Change[R, P]
+	for _, id := range ids {
+		role, err := rbac.Get(ctx, id)
+		if err != nil {
+			return Plan[R, P]{}, err
+		}
+		imps := []*gorbac.Implications[P]{gorbac.ImplicationsOf[P](rbac), gorbac.ImplicationsOf[P](role)}
+		var unused []gorbac.Permission[P]
+		for _, p := range role.Permissions(ctx) {
+			_, done := exercised[id][p.ID()]
+			_, maybe := uncertain[p.ID()]
+			if !done && !maybe && evaluable(ctx, p, imps) {
+				unused = append(unused, p)
+			}
+		}
+		if len(unused) > 0 {
+			sortPermissions(unused)
+			revokes = append(revokes, Change[R, P]{
+				Kind:        ChangeRevoke,
+				Role:        id,
+				Permissions: unused,
+				Reason:      "never exercised" + cfg.period(),
+			})
+		}
+
+		if narrowID == nil || len(used[id]) == 0 {
+			continue
+		}
+		effective, err := gorbac.EffectivePermissions(ctx, rbac, id)
+		if err != nil {
+			return Plan[R, P]{}, err
+		}
+		var narrow []gorbac.Permission[P]
+		for _, p := range effective {
+			if _, ok := used[id][p.ID()]; ok {
+				narrow = append(narrow, p)
+			}
+		}
+		if len(narrow) == 0 || float64(len(narrow)) > cfg.maxUsage*float64(len(effective)) {
+			continue
+		}
+		sortPermissions(narrow)
+		var who []string
+		for s := range subjects[id] {
+			who = append(who, s)
+		}
+		slices.Sort(who)
+		plan.Changes = append(plan.Changes, Change[R, P]{
+			Kind:        ChangeAddRole,
+			Role:        narrowID(id),
+			Permissions: narrow,
+			Base:        id,
+			Subjects:    who,
+			Reason:      fmt.Sprintf("narrows %v: %d of %d permissions exercised%s", id, len(narrow), len(effective), cfg.period()),
+		})
+	}
+	plan.Changes = append(plan.Changes, revokes...)
+	return plan, nil
+}
+
+// evaluable reports whether the use of `p` can be told from the permission
+// IDs of decisions.
+func evaluable[P comparable](ctx context.Context, p gorbac.Permission[P], imps []*gorbac.Implications[P]) bool {
+	record, err := gorbac.RecordOf(p)
+	if err != nil || record.Sep != "" || record.Filter != "" {
+		return false
+	}
+	for _, imp := range imps {
+		if imp != nil && len(imp.GetImplies(ctx, p.ID())) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (cfg *config) in(t time.Time) bool {
+	if !cfg.from.IsZero() && t.Before(cfg.from) {
+		return false
+	}
+	return cfg.to.IsZero() || t.Before(cfg.to)
+}
+
+func (cfg *config) period() string {
+	const layout = time.DateOnly
+	switch {
+	case !cfg.from.IsZero() && !cfg.to.IsZero():
+		return fmt.Sprintf(" from %s until %s", cfg.from.Format(layout), cfg.to.Format(layout))
+	case !cfg.from.IsZero():
+		return " since " + cfg.from.Format(layout)
+	case !cfg.to.IsZero():
+		return " until " + cfg.to.Format(layout)
+	default:
+		return ""
+	}
+}
+
+func mark[K, V comparable](m map[K]map[V]struct{}, k K, v V) {
+	if m[k] == nil {
+		m[k] = make(map[V]struct{})
+	}
+	m[k][v] = struct{}{}
+}
+
+func sortPermissions[P comparable](perms []gorbac.Permission[P]) {
+	slices.SortFunc(perms, func(a, b gorbac.Permission[P]) int {
+		return cmp.Compare(fmt.Sprint(a.ID()), fmt.Sprint(b.ID()))
+	})
+}
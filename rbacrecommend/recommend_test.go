@@ -0,0 +1,144 @@
+package rbacrecommend
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fixture(t *testing.T) *gorbac.StdRBAC[string] {
+	ctx := context.Background()
+	rbac := gorbac.New[string]()
+	roles := map[string][]string{
+		"viewer": {"read", "export"},
+		"editor": {"write", "publish", "archive", "comment"},
+	}
+	for id, perms := range roles {
+		role := gorbac.NewRole(id)
+		for _, p := range perms {
+			must(t, role.Assign(ctx, gorbac.NewPermission(p)))
+		}
+		must(t, rbac.Add(ctx, role))
+	}
+	must(t, rbac.SetParents(ctx, "editor", "viewer"))
+	return rbac
+}
+
+func TestRecommend(t *testing.T) {
+	ctx := context.Background()
+	rbac := fixture(t)
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	decisions := []gorbac.Decision[string, string]{
+		{Time: day, Subject: "alice", Roles: []string{"editor"}, Permissions: []string{"read"}, Granted: true},
+		{Time: day, Subject: "bob", Roles: []string{"editor"}, Permissions: []string{"write"}, Granted: true},
+		{Time: day, Subject: "bob", Roles: []string{"editor"}, Permissions: []string{"delete"}, Granted: false},
+		// outside the period
+		{Time: day.AddDate(0, -2, 0), Subject: "carol", Roles: []string{"editor"}, Permissions: []string{"publish"}, Granted: true},
+	}
+	plan, err := Recommend(ctx, rbac, decisions,
+		WithPeriod(day.AddDate(0, -1, 0), time.Time{}),
+		WithNarrowRoleID(func(id string) string { return id + "-minimal" }))
+	must(t, err)
+
+	want := "add-role editor-minimal: read, write (narrows editor: 2 of 6 permissions exercised since 2026-09-01)\n" +
+		"revoke editor: archive, comment, publish (never exercised since 2026-09-01)\n" +
+		"revoke viewer: export (never exercised since 2026-09-01)\n"
+	if got := plan.String(); got != want {
+		t.Fatalf("unexpected plan:\n%s", got)
+	}
+	if subjects := plan.Changes[0].Subjects; !slices.Equal(subjects, []string{"alice", "bob"}) {
+		t.Fatalf("unexpected subjects %v", subjects)
+	}
+
+	must(t, plan.Apply(ctx, gorbac.NewCached[string, string](rbac)))
+	if !rbac.IsGranted(ctx, "editor-minimal", gorbac.NewPermission("write")) {
+		t.Fatal("the narrow role should hold write")
+	}
+	if rbac.IsGranted(ctx, "editor", gorbac.NewPermission("publish")) ||
+		!rbac.IsGranted(ctx, "editor", gorbac.NewPermission("read")) {
+		t.Fatal("only the unexercised permissions should be revoked")
+	}
+	if err := plan.Apply(ctx, rbac); err == nil {
+		t.Fatal("adding the narrow role twice should fail")
+	}
+}
+
+func TestRecommendThreshold(t *testing.T) {
+	ctx := context.Background()
+	rbac := fixture(t)
+	decisions := []gorbac.Decision[string, string]{
+		{Roles: []string{"viewer"}, Permissions: []string{"read"}, Granted: true},
+	}
+	plan, err := Recommend(ctx, rbac, decisions,
+		WithNarrowRoleID(func(id string) string { return id + "-minimal" }), WithMaxUsage(0.4))
+	must(t, err)
+	for _, c := range plan.Changes {
+		if c.Kind == ChangeAddRole {
+			t.Fatalf("1 of 2 permissions is above the threshold, got %v", c)
+		}
+	}
+	if len(plan.Changes) != 2 || plan.Changes[1].Role != "viewer" || plan.Changes[1].Reason != "never exercised" {
+		t.Fatalf("unexpected plan:\n%s", plan)
+	}
+}
+
+func TestRecommendUnevaluable(t *testing.T) {
+	ctx := context.Background()
+	rbac := gorbac.New[string]()
+	admin := gorbac.NewRole("admin")
+	must(t, admin.Assign(ctx,
+		gorbac.NewLayerPermission("admin", "::"),
+		gorbac.NewFilterPermission("orders", "owner_id == uid"),
+		gorbac.NewPermission("write"),
+		gorbac.NewPermission("unused")))
+	must(t, rbac.Add(ctx, admin))
+	imp := gorbac.NewImplications[string]()
+	must(t, imp.SetImplies(ctx, "write", "read"))
+	rbac.SetImplications(imp)
+
+	decisions := []gorbac.Decision[string, string]{
+		{Roles: []string{"admin"}, Permissions: []string{"admin::dashboard"}, Granted: true},
+		{Roles: []string{"admin"}, Permissions: []string{"read"}, Granted: true},
+	}
+	plan, err := Recommend(ctx, rbac, decisions)
+	must(t, err)
+	if got, want := plan.String(), "revoke admin: unused (never exercised)\n"; got != want {
+		t.Fatalf("only evaluable permissions should be revoked, got:\n%s", got)
+	}
+}
+
+func TestRecommendUncertain(t *testing.T) {
+	ctx := context.Background()
+	rbac := fixture(t)
+	var decisions []gorbac.Decision[string, string]
+	rbac.SetDecisionLogger(gorbac.DecisionLoggerFunc[string, string](func(_ context.Context, d gorbac.Decision[string, string]) {
+		decisions = append(decisions, d)
+	}))
+	// read is granted, delete is not: the decision does not tell which
+	if gorbac.AllGranted(ctx, rbac, []string{"viewer"}, gorbac.NewPermission("read"), gorbac.NewPermission("delete")) {
+		t.Fatal("viewer should not have delete")
+	}
+	plan, err := Recommend(ctx, rbac, decisions)
+	must(t, err)
+	want := "revoke editor: archive, comment, publish, write (never exercised)\n" +
+		"revoke viewer: export (never exercised)\n"
+	if got := plan.String(); got != want {
+		t.Fatalf("read may have been exercised, got:\n%s", got)
+	}
+
+	_, err = Recommend(ctx, rbac, decisions, WithNarrowRoleID(func(id int) int { return id + 1 }))
+	if !errors.Is(err, ErrNarrowRoleID) {
+		t.Fatalf("%s expected, but %v got", ErrNarrowRoleID, err)
+	}
+}
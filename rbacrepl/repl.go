@@ -0,0 +1,279 @@
+// Package rbacrepl replicates a role model between processes over its
+// ordered change log.
+//
+// The leader records its mutations with gorbac.NewVersioned and streams them
+// to followers, each keeping its own in-memory RBAC:
+//
+//	versioned, _ := gorbac.NewVersioned(ctx, gorbac.New[string]())
+//	leader := rbacrepl.NewLeader[string, string](versioned)
+//	leader.Attach(ctx, transport) // sends a snapshot to bootstrap the replica
+//	go leader.Run(ctx, time.Second)
+//
+//	follower := rbacrepl.NewFollower[string, string](gorbac.New[string]())
+//	err := follower.Run(ctx, transport)
+//
+// A follower attached to a running leader first receives a snapshot of the
+// current state, then every later event in version order.
+package rbacrepl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+// ErrGap occurred if a follower receives an event that does not follow the
+// version it is at
+var ErrGap = errors.New("Events are missing from the change log")
+
+// ChangeLog is an ordered log of role model mutations.
+// gorbac.VersionedRBACOf implements it.
+type ChangeLog[R, P comparable] interface {
+	// Snapshot returns the current version and the events rebuilding the
+	// role model at that version.
+	Snapshot(ctx context.Context) (uint64, []gorbac.Event[R, P], error)
+	// Events returns the events after version `from`, oldest first.
+	Events(from uint64) []gorbac.Event[R, P]
+}
+
+// Message is the unit sent over a Transport.
+type Message[R, P comparable] struct {
+	// Snapshot is set when Events rebuild the whole role model at Version.
+	Snapshot bool                 `json:"snapshot,omitempty"`
+	Version  uint64               `json:"version"`
+	Events   []gorbac.Event[R, P] `json:"events"`
+}
+
+// Transport carries messages from a leader to one follower.
+type Transport[R, P comparable] interface {
+	Send(ctx context.Context, m Message[R, P]) error
+	// Receive blocks until a message arrives; io.EOF is returned once the
+	// transport is closed and drained.
+	Receive(ctx context.Context) (Message[R, P], error)
+	Close() error
+}
+
+type peer[R, P comparable] struct {
+	transport Transport[R, P]
+	// mutex serializes the sends to the peer
+	mutex   sync.Mutex
+	version uint64
+}
+
+// Leader streams a change log to followers. Every follower is sent to
+// concurrently, so a slow one does not hold back the others.
+type Leader[R, P comparable] struct {
+	log ChangeLog[R, P]
+	// mutex guards peers; it is not held while sending
+	mutex sync.Mutex
+	peers []*peer[R, P]
+}
+
+// NewLeader returns a leader streaming `log`.
+func NewLeader[R, P comparable](log ChangeLog[R, P]) *Leader[R, P] {
+	return &Leader[R, P]{log: log}
+}
+
+// Attach bootstraps a follower with a snapshot and streams the later events
+// to it on every Publish.
+func (l *Leader[R, P]) Attach(ctx context.Context, t Transport[R, P]) error {
+	version, events, err := l.log.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	if err := t.Send(ctx, Message[R, P]{Snapshot: true, Version: version, Events: events}); err != nil {
+		return err
+	}
+	l.mutex.Lock()
+	l.peers = append(l.peers, &peer[R, P]{transport: t, version: version})
+	l.mutex.Unlock()
+	return nil
+}
+
+// Followers returns the number of attached followers.
+func (l *Leader[R, P]) Followers() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.peers)
+}
+
+// Publish sends every follower the events it has not received yet. A
+// follower whose transport fails is detached, its transport closed, and its
+// error returned.
+func (l *Leader[R, P]) Publish(ctx context.Context) error {
+	l.mutex.Lock()
+	peers := slices.Clone(l.peers)
+	l.mutex.Unlock()
+
+	errs := make([]error, len(peers))
+	var wg sync.WaitGroup
+	for i, p := range peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = p.publish(ctx, l.log)
+		}()
+	}
+	wg.Wait()
+
+	var failed []*peer[R, P]
+	for i, p := range peers {
+		if errs[i] != nil {
+			failed = append(failed, p)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	l.mutex.Lock()
+	l.peers = slices.DeleteFunc(l.peers, func(p *peer[R, P]) bool { return slices.Contains(failed, p) })
+	l.mutex.Unlock()
+	for _, p := range failed {
+		_ = p.transport.Close()
+	}
+	return errors.Join(errs...)
+}
+
+// publish sends the peer the events of `log` it has not received yet.
+func (p *peer[R, P]) publish(ctx context.Context, log ChangeLog[R, P]) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	events := log.Events(p.version)
+	if len(events) == 0 {
+		return nil
+	}
+	version := events[len(events)-1].Version
+	if err := p.transport.Send(ctx, Message[R, P]{Version: version, Events: events}); err != nil {
+		return err
+	}
+	p.version = version
+	return nil
+}
+
+// Run publishes every `interval` until `ctx` is done, then closes the
+// transports of the attached followers. Publish errors are returned through
+// `onError` when given.
+func (l *Leader[R, P]) Run(ctx context.Context, interval time.Duration, onError ...func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			l.mutex.Lock()
+			peers := l.peers
+			l.peers = nil
+			l.mutex.Unlock()
+			var errs []error
+			for _, p := range peers {
+				errs = append(errs, p.transport.Close())
+			}
+			return errors.Join(errs...)
+		case <-ticker.C:
+			if err := l.Publish(ctx); err != nil && len(onError) > 0 && onError[0] != nil {
+				onError[0](err)
+			}
+		}
+	}
+}
+
+// Follower applies the messages of a leader to a local RBAC.
+type Follower[R, P comparable] struct {
+	rbac    gorbac.RBACOf[R, P]
+	mutex   sync.Mutex
+	version uint64
+	synced  bool
+}
+
+// NewFollower returns a follower replicating into `rbac`.
+func NewFollower[R, P comparable](rbac gorbac.RBACOf[R, P]) *Follower[R, P] {
+	return &Follower[R, P]{rbac: rbac}
+}
+
+// Version returns the version of the last applied event.
+func (f *Follower[R, P]) Version() uint64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.version
+}
+
+// Apply applies one message. A snapshot replaces the local role model;
+// other messages must continue from the current version, or ErrGap is
+// returned. Events already applied are skipped. When the RBAC has an
+// `InvalidateAll()` method, such as gorbac.CachedRBAC, it is called after
+// every change.
+//
+// A snapshot is built into a new RBAC first. A gorbac.StdRBACOf swaps it in
+// at once with Replace; other RBACs are changed one event at a time, and
+// when one fails, the follower is out of sync and returns ErrGap until the
+// next snapshot.
+func (f *Follower[R, P]) Apply(ctx context.Context, m Message[R, P]) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if inv, ok := f.rbac.(interface{ InvalidateAll() }); ok {
+		defer inv.InvalidateAll()
+	}
+	if m.Snapshot {
+		target := gorbac.NewOf[R, P]()
+		for _, e := range m.Events {
+			if err := gorbac.ApplyEvent(ctx, target, e); err != nil {
+				return fmt.Errorf("snapshot: %w", err)
+			}
+		}
+		if std, ok := f.rbac.(*gorbac.StdRBACOf[R, P]); ok {
+			std.Replace(target)
+		} else {
+			events, err := gorbac.DiffEvents(ctx, f.rbac, gorbac.RBACOf[R, P](target))
+			if err != nil {
+				return err
+			}
+			for _, e := range events {
+				if err := gorbac.ApplyEvent(ctx, f.rbac, e); err != nil {
+					f.synced = false
+					return fmt.Errorf("snapshot: %w", err)
+				}
+			}
+		}
+		f.version, f.synced = m.Version, true
+		return nil
+	}
+	if !f.synced {
+		return fmt.Errorf("%w: no snapshot received", ErrGap)
+	}
+	for _, e := range m.Events {
+		if e.Version <= f.version {
+			continue
+		}
+		if e.Version != f.version+1 {
+			return fmt.Errorf("%w: version %d after %d", ErrGap, e.Version, f.version)
+		}
+		if err := gorbac.ApplyEvent(ctx, f.rbac, e); err != nil {
+			f.synced = false
+			return fmt.Errorf("version %d: %w", e.Version, err)
+		}
+		f.version = e.Version
+	}
+	return nil
+}
+
+// Run applies the messages received on `t` until it is closed, an error
+// occurs or `ctx` is done. A closed transport returns nil.
+func (f *Follower[R, P]) Run(ctx context.Context, t Transport[R, P]) error {
+	for {
+		m, err := t.Receive(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := f.Apply(ctx, m); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package rbacrepl_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fy0/gorbac/v3"
+	"github.com/fy0/gorbac/v3/rbacrepl"
+)
+
+var (
+	read  = gorbac.NewPermission("read")
+	write = gorbac.NewPermission("write")
+)
+
+func newLeader(t *testing.T) (*gorbac.VersionedRBAC[string], *rbacrepl.Leader[string, string]) {
+	t.Helper()
+	ctx := context.Background()
+	std := gorbac.New[string]()
+	viewer := gorbac.NewRole("viewer")
+	viewer.Assign(ctx, read)
+	if err := std.Add(ctx, viewer); err != nil {
+		t.Fatal(err)
+	}
+	versioned, err := gorbac.NewVersioned(ctx, std)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return versioned, rbacrepl.NewLeader[string, string](versioned)
+}
+
+func roleIDs(rbac gorbac.RBAC[string]) []string {
+	ids := rbac.RoleIDs(context.Background())
+	slices.Sort(ids)
+	return ids
+}
+
+func TestReplication(t *testing.T) {
+	ctx := context.Background()
+	versioned, leader := newLeader(t)
+
+	early := rbacrepl.NewInProcess[string, string](16)
+	if err := leader.Attach(ctx, early); err != nil {
+		t.Fatal(err)
+	}
+	replica := gorbac.New[string]()
+	follower := rbacrepl.NewFollower[string, string](replica)
+	done := make(chan error, 1)
+	go func() { done <- follower.Run(ctx, early) }()
+
+	editor := gorbac.NewRole("editor")
+	editor.Assign(ctx, write)
+	if err := versioned.Add(ctx, editor); err != nil {
+		t.Fatal(err)
+	}
+	if err := versioned.SetParents(ctx, "editor", "viewer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := leader.Publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new replica is bootstrapped from a snapshot
+	late := rbacrepl.NewInProcess[string, string](16)
+	if err := leader.Attach(ctx, late); err != nil {
+		t.Fatal(err)
+	}
+	lateReplica := gorbac.NewCached[string, string](gorbac.New[string]())
+	lateFollower := rbacrepl.NewFollower[string, string](lateReplica)
+	lateDone := make(chan error, 1)
+	go func() { lateDone <- lateFollower.Run(ctx, late) }()
+
+	if err := versioned.Remove(ctx, "viewer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := leader.Publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if leader.Followers() != 2 {
+		t.Fatalf("2 followers expected, but %d got", leader.Followers())
+	}
+	early.Close()
+	late.Close()
+	for _, ch := range []chan error{done, lateDone} {
+		if err := <-ch; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, rbac := range []gorbac.RBAC[string]{replica, lateReplica} {
+		if !slices.Equal(roleIDs(rbac), []string{"editor"}) {
+			t.Fatalf("unexpected roles %v", roleIDs(rbac))
+		}
+		if !rbac.IsGranted(ctx, "editor", write) || rbac.IsGranted(ctx, "editor", read) {
+			t.Fatal("the replica should converge with the leader")
+		}
+	}
+	if follower.Version() != versioned.Version() || lateFollower.Version() != versioned.Version() {
+		t.Fatalf("version %d expected, but %d and %d got", versioned.Version(), follower.Version(), lateFollower.Version())
+	}
+
+	// a failing transport detaches its follower
+	if err := versioned.Add(ctx, gorbac.NewRole("viewer")); err != nil {
+		t.Fatal(err)
+	}
+	if err := leader.Publish(ctx); !errors.Is(err, rbacrepl.ErrTransportClosed) || leader.Followers() != 0 {
+		t.Fatalf("unexpected publish result %v, %d followers", err, leader.Followers())
+	}
+}
+
+func TestFollowerGap(t *testing.T) {
+	ctx := context.Background()
+	follower := rbacrepl.NewFollower[string, string](gorbac.New[string]())
+	event := gorbac.Event[string, string]{Version: 1, Op: gorbac.EventAddRole, Role: "viewer"}
+	if err := follower.Apply(ctx, rbacrepl.Message[string, string]{Version: 1, Events: []gorbac.Event[string, string]{event}}); !errors.Is(err, rbacrepl.ErrGap) {
+		t.Fatalf("%s expected, but %v got", rbacrepl.ErrGap, err)
+	}
+	if err := follower.Apply(ctx, rbacrepl.Message[string, string]{Snapshot: true}); err != nil {
+		t.Fatal(err)
+	}
+	event.Version = 2
+	if err := follower.Apply(ctx, rbacrepl.Message[string, string]{Version: 2, Events: []gorbac.Event[string, string]{event}}); !errors.Is(err, rbacrepl.ErrGap) {
+		t.Fatalf("%s expected, but %v got", rbacrepl.ErrGap, err)
+	}
+	event.Version = 1
+	for range 2 {
+		// the second delivery is skipped
+		if err := follower.Apply(ctx, rbacrepl.Message[string, string]{Version: 1, Events: []gorbac.Event[string, string]{event}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if follower.Version() != 1 {
+		t.Fatalf("version 1 expected, but %d got", follower.Version())
+	}
+}
+
+func TestLeaderRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	versioned, leader := newLeader(t)
+	transport := rbacrepl.NewInProcess[string, string](16)
+	if err := leader.Attach(ctx, transport); err != nil {
+		t.Fatal(err)
+	}
+	stopped := make(chan error, 1)
+	go func() { stopped <- leader.Run(ctx, time.Millisecond) }()
+
+	if err := versioned.Add(ctx, gorbac.NewRole("editor")); err != nil {
+		t.Fatal(err)
+	}
+	replica := gorbac.New[string]()
+	follower := rbacrepl.NewFollower[string, string](replica)
+	deadline := time.Now().Add(5 * time.Second)
+	for follower.Version() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("the event should be published")
+		}
+		m, err := transport.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := follower.Apply(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+	if err := transport.Send(context.Background(), rbacrepl.Message[string, string]{}); !errors.Is(err, rbacrepl.ErrTransportClosed) {
+		t.Fatalf("%s expected, but %v got", rbacrepl.ErrTransportClosed, err)
+	}
+	if !slices.Equal(roleIDs(replica), []string{"editor", "viewer"}) {
+		t.Fatalf("unexpected roles %v", roleIDs(replica))
+	}
+}
+
+func TestFollowerSnapshotAtomic(t *testing.T) {
+	ctx := context.Background()
+	replica := gorbac.New[string]()
+	if err := replica.Add(ctx, gorbac.NewRole("old")); err != nil {
+		t.Fatal(err)
+	}
+	follower := rbacrepl.NewFollower[string, string](replica)
+	bad := rbacrepl.Message[string, string]{Snapshot: true, Version: 2, Events: []gorbac.Event[string, string]{
+		{Op: gorbac.EventAddRole, Role: "viewer"},
+		{Op: gorbac.EventSetParents, Role: "viewer", Parents: []string{"missing"}},
+	}}
+	if err := follower.Apply(ctx, bad); err == nil {
+		t.Fatal("a broken snapshot should fail")
+	}
+	if !slices.Equal(roleIDs(replica), []string{"old"}) || follower.Version() != 0 {
+		t.Fatalf("a broken snapshot should change nothing, got %v at %d", roleIDs(replica), follower.Version())
+	}
+	bad.Events = bad.Events[:1]
+	if err := follower.Apply(ctx, bad); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(roleIDs(replica), []string{"viewer"}) || follower.Version() != 2 {
+		t.Fatalf("the snapshot should replace the role model, got %v at %d", roleIDs(replica), follower.Version())
+	}
+}
+
+// slowTransport blocks every Send after the snapshot until release yields
+// its result.
+type slowTransport struct {
+	rbacrepl.Transport[string, string]
+	sends   int
+	release chan error
+	closed  chan struct{}
+}
+
+func (s *slowTransport) Send(ctx context.Context, m rbacrepl.Message[string, string]) error {
+	if s.sends++; s.sends == 1 {
+		return nil
+	}
+	return <-s.release
+}
+
+func (s *slowTransport) Close() error {
+	close(s.closed)
+	return nil
+}
+
+func TestLeaderSlowFollower(t *testing.T) {
+	ctx := context.Background()
+	versioned, leader := newLeader(t)
+	slow := &slowTransport{release: make(chan error), closed: make(chan struct{})}
+	if err := leader.Attach(ctx, slow); err != nil {
+		t.Fatal(err)
+	}
+	if err := versioned.Add(ctx, gorbac.NewRole("editor")); err != nil {
+		t.Fatal(err)
+	}
+	published := make(chan error, 1)
+	go func() { published <- leader.Publish(ctx) }()
+
+	// the blocked send holds back neither Attach nor the other followers
+	fast := rbacrepl.NewInProcess[string, string](16)
+	attached := make(chan error, 1)
+	go func() { attached <- leader.Attach(ctx, fast) }()
+	select {
+	case err := <-attached:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Attach should not wait for a slow follower")
+	}
+	if leader.Followers() != 2 {
+		t.Fatalf("2 followers expected, but %d got", leader.Followers())
+	}
+
+	slow.release <- rbacrepl.ErrTransportClosed
+	if err := <-published; !errors.Is(err, rbacrepl.ErrTransportClosed) {
+		t.Fatalf("%s expected, but %v got", rbacrepl.ErrTransportClosed, err)
+	}
+	select {
+	case <-slow.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a detached follower should be closed")
+	}
+	if leader.Followers() != 1 {
+		t.Fatalf("the slow follower should be detached, %d followers got", leader.Followers())
+	}
+}
@@ -0,0 +1,130 @@
+package rbacrepl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrTransportClosed occurred if a message is sent on a closed transport
+var ErrTransportClosed = errors.New("Transport is closed")
+
+// InProcess is a Transport between goroutines of one process.
+type InProcess[R, P comparable] struct {
+	messages chan Message[R, P]
+	mutex    sync.RWMutex
+	closed   bool
+}
+
+// NewInProcess returns an in-process transport buffering up to `size`
+// messages; Send blocks while the buffer is full.
+func NewInProcess[R, P comparable](size int) *InProcess[R, P] {
+	return &InProcess[R, P]{messages: make(chan Message[R, P], size)}
+}
+
+// Send queues `m` for the follower.
+func (t *InProcess[R, P]) Send(ctx context.Context, m Message[R, P]) error {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	if t.closed {
+		return ErrTransportClosed
+	}
+	select {
+	case t.messages <- m:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Receive returns the next message.
+func (t *InProcess[R, P]) Receive(ctx context.Context) (Message[R, P], error) {
+	select {
+	case m, ok := <-t.messages:
+		if !ok {
+			return Message[R, P]{}, io.EOF
+		}
+		return m, nil
+	case <-ctx.Done():
+		return Message[R, P]{}, ctx.Err()
+	}
+}
+
+// Close stops the transport; the follower still receives the queued
+// messages.
+func (t *InProcess[R, P]) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.closed {
+		t.closed = true
+		close(t.messages)
+	}
+	return nil
+}
+
+// Stream is a Transport writing messages as JSON lines, e.g. over a pipe, a
+// network connection or into a file replayed later.
+type Stream[R, P comparable] struct {
+	r       io.Reader
+	w       io.Writer
+	decoder *json.Decoder
+	mutex   sync.Mutex
+}
+
+// NewStream returns a transport sending to `w` and receiving from `r`;
+// either may be nil on the side that does not use it. Receive blocks on `r`
+// regardless of the context.
+func NewStream[R, P comparable](r io.Reader, w io.Writer) *Stream[R, P] {
+	t := &Stream[R, P]{r: r, w: w}
+	if r != nil {
+		t.decoder = json.NewDecoder(r)
+	}
+	return t
+}
+
+// Send writes `m` as one JSON line.
+func (t *Stream[R, P]) Send(ctx context.Context, m Message[R, P]) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.w == nil {
+		return ErrTransportClosed
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	_, err = t.w.Write(append(data, '\n'))
+	return err
+}
+
+// Receive reads the next message; io.EOF is returned at the end of `r`.
+func (t *Stream[R, P]) Receive(ctx context.Context) (Message[R, P], error) {
+	var m Message[R, P]
+	if err := ctx.Err(); err != nil {
+		return m, err
+	}
+	if t.decoder == nil {
+		return m, io.EOF
+	}
+	err := t.decoder.Decode(&m)
+	return m, err
+}
+
+// Close closes `r` and `w` when they are io.Closers, once when they are
+// the same, e.g. a net.Conn.
+func (t *Stream[R, P]) Close() error {
+	var errs []error
+	rc, rok := t.r.(io.Closer)
+	if rok {
+		errs = append(errs, rc.Close())
+	}
+	if wc, ok := t.w.(io.Closer); ok && (!rok || wc != rc) {
+		errs = append(errs, wc.Close())
+	}
+	return errors.Join(errs...)
+}
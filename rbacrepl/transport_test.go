@@ -0,0 +1,104 @@
+package rbacrepl_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fy0/gorbac/v3"
+	"github.com/fy0/gorbac/v3/rbacrepl"
+)
+
+func TestStreamPipe(t *testing.T) {
+	ctx := context.Background()
+	versioned, leader := newLeader(t)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica := gorbac.New[string]()
+	follower := rbacrepl.NewFollower[string, string](replica)
+	done := make(chan error, 1)
+	go func() { done <- follower.Run(ctx, rbacrepl.NewStream[string, string](r, nil)) }()
+
+	sender := rbacrepl.NewStream[string, string](nil, w)
+	if err := leader.Attach(ctx, sender); err != nil {
+		t.Fatal(err)
+	}
+	editor := gorbac.NewRole("editor")
+	editor.Assign(ctx, gorbac.NewLayerPermission("posts:write", ":"))
+	if err := versioned.Add(ctx, editor); err != nil {
+		t.Fatal(err)
+	}
+	if err := versioned.SetParents(ctx, "editor", "viewer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := leader.Publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !replica.IsGranted(ctx, "editor", gorbac.NewLayerPermission("posts:write:draft", ":")) ||
+		!replica.IsGranted(ctx, "editor", read) {
+		t.Fatal("the replica should converge with the leader")
+	}
+	if follower.Version() != 2 {
+		t.Fatalf("version 2 expected, but %d got", follower.Version())
+	}
+}
+
+func TestStreamFile(t *testing.T) {
+	ctx := context.Background()
+	versioned, leader := newLeader(t)
+	name := filepath.Join(t.TempDir(), "changes.jsonl")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender := rbacrepl.NewStream[string, string](nil, f)
+	if err := leader.Attach(ctx, sender); err != nil {
+		t.Fatal(err)
+	}
+	role, err := versioned.Get(gorbac.WithSubject(ctx, "alice"), "viewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := role.Assign(gorbac.WithSubject(ctx, "alice"), write); err != nil {
+		t.Fatal(err)
+	}
+	if err := leader.Publish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Fatalf("2 messages expected, but %d got", lines)
+	}
+	if !strings.Contains(string(data), `"actor":"alice"`) {
+		t.Fatalf("the actor should be replicated: %s", data)
+	}
+
+	// replay the file into a replica holding stale state
+	replica := gorbac.New[string]()
+	replica.Add(ctx, gorbac.NewRole("stale"))
+	follower := rbacrepl.NewFollower[string, string](replica)
+	if err := follower.Run(ctx, rbacrepl.NewStream[string, string](bytes.NewReader(data), nil)); err != nil {
+		t.Fatal(err)
+	}
+	if !replica.IsGranted(ctx, "viewer", write) || len(replica.RoleIDs(ctx)) != 1 {
+		t.Fatalf("unexpected replica roles %v", replica.RoleIDs(ctx))
+	}
+}
@@ -0,0 +1,392 @@
+// Package rbactest provides conformance tests for RBAC and Role
+// implementations.
+//
+// The helpers of gorbac (Walk, InherCircle, EffectivePermissions, the filter
+// scope, ...) rely on the semantics of StdRBAC and StdRole. Custom
+// implementations check that they follow them from their own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		rbactest.TestRBAC(t, rbactest.Config[string]{
+//			NewRBAC: func(t *testing.T) gorbac.RBAC[string] { return NewMyRBAC(t) },
+//			ID:      rbactest.StringID,
+//		})
+//		rbactest.TestRole(t, rbactest.Config[string]{
+//			NewRole: func(id string) gorbac.Role[string] { return NewMyRole(id) },
+//			ID:      rbactest.StringID,
+//		})
+//	}
+//
+// Implementations with distinct role and permission ID types use ConfigOf
+// with both generators:
+//
+//	rbactest.TestRBAC(t, rbactest.ConfigOf[int64, string]{
+//		NewRBAC:      func(t *testing.T) gorbac.RBACOf[int64, string] { return NewMyRBAC(t) },
+//		ID:           func(n int) int64 { return int64(n) },
+//		PermissionID: rbactest.StringID,
+//	})
+package rbactest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/fy0/gorbac/v3"
+)
+
+// ConfigOf describes the implementation under test.
+type ConfigOf[R, P comparable] struct {
+	// NewRBAC returns an empty RBAC. It is called once per subtest and is
+	// required by TestRBAC.
+	NewRBAC func(t *testing.T) gorbac.RBACOf[R, P]
+	// NewRole returns a role accepted by the RBAC, gorbac.NewRoleOf by default.
+	NewRole func(id R) gorbac.RoleOf[R, P]
+	// ID returns a distinct role ID for every `n`.
+	ID func(n int) R
+	// PermissionID returns a distinct permission ID for every `n`. It may be
+	// left nil when role and permission IDs share a type, and ID is used.
+	PermissionID func(n int) P
+	// SkipConcurrency skips the subtest calling the RBAC from several
+	// goroutines, for implementations which are not safe for concurrent use.
+	SkipConcurrency bool
+}
+
+// Config is a ConfigOf where role IDs and permission IDs share the type T.
+type Config[T comparable] = ConfigOf[T, T]
+
+// StringID is a Config.ID for string IDs.
+func StringID(n int) string {
+	return fmt.Sprintf("id-%d", n)
+}
+
+func (cfg ConfigOf[R, P]) role(id R) gorbac.RoleOf[R, P] {
+	if cfg.NewRole != nil {
+		return cfg.NewRole(id)
+	}
+	return gorbac.NewRoleOf[R, P](id)
+}
+
+func (cfg *ConfigOf[R, P]) check(t *testing.T) {
+	t.Helper()
+	if cfg.ID == nil {
+		t.Fatal("rbactest: Config.ID is required")
+	}
+	if cfg.PermissionID == nil {
+		id, ok := any(cfg.ID).(func(int) P)
+		if !ok {
+			t.Fatal("rbactest: Config.PermissionID is required for distinct ID types")
+		}
+		cfg.PermissionID = id
+	}
+}
+
+// fixture adds the roles 0..n-1; role i holds permission 100+i.
+func (cfg ConfigOf[R, P]) fixture(t *testing.T, n int) (gorbac.RBACOf[R, P], []R) {
+	t.Helper()
+	ctx := context.Background()
+	rbac := cfg.NewRBAC(t)
+	ids := make([]R, n)
+	for i := range ids {
+		ids[i] = cfg.ID(i)
+		role := cfg.role(ids[i])
+		if err := role.Assign(ctx, cfg.perm(i)); err != nil {
+			t.Fatalf("Assign: %v", err)
+		}
+		if err := rbac.Add(ctx, role); err != nil {
+			t.Fatalf("Add(%v): %v", ids[i], err)
+		}
+	}
+	return rbac, ids
+}
+
+// perm returns the permission held by the role i of a fixture.
+func (cfg ConfigOf[R, P]) perm(i int) gorbac.Permission[P] {
+	return gorbac.NewPermission(cfg.PermissionID(100 + i))
+}
+
+func setParents[R, P comparable](t *testing.T, rbac gorbac.RBACOf[R, P], id R, parents ...R) {
+	t.Helper()
+	if err := rbac.SetParents(context.Background(), id, parents...); err != nil {
+		t.Fatalf("SetParents(%v, %v): %v", id, parents, err)
+	}
+}
+
+func sameSet[T comparable](a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, v := range a {
+		if !slices.Contains(b, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func wantErr(t *testing.T, call string, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Errorf("%s = %v, want %v", call, err, target)
+	}
+}
+
+// TestRBAC runs the RBAC conformance suite as subtests of `t`.
+func TestRBAC[R, P comparable](t *testing.T, cfg ConfigOf[R, P]) {
+	t.Helper()
+	cfg.check(t)
+	if cfg.NewRBAC == nil {
+		t.Fatal("rbactest: Config.NewRBAC is required")
+	}
+	ctx := context.Background()
+
+	t.Run("Add", func(t *testing.T) {
+		rbac, ids := cfg.fixture(t, 1)
+		wantErr(t, "Add(duplicate)", rbac.Add(ctx, cfg.role(ids[0])), gorbac.ErrRoleExist)
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		rbac, ids := cfg.fixture(t, 1)
+		role, err := rbac.Get(ctx, ids[0])
+		if err != nil || role == nil || role.ID() != ids[0] {
+			t.Errorf("Get(%v) = %v, %v", ids[0], role, err)
+		}
+		_, err = rbac.Get(ctx, cfg.ID(99))
+		wantErr(t, "Get(missing)", err, gorbac.ErrRoleNotExist)
+	})
+
+	t.Run("RoleIDs", func(t *testing.T) {
+		rbac, ids := cfg.fixture(t, 3)
+		if got := rbac.RoleIDs(ctx); !sameSet(got, ids) {
+			t.Errorf("RoleIDs() = %v, want %v", got, ids)
+		}
+	})
+
+	t.Run("SetParents", func(t *testing.T) {
+		rbac, ids := cfg.fixture(t, 3)
+		wantErr(t, "SetParents(missing role)", rbac.SetParents(ctx, cfg.ID(99), ids[0]), gorbac.ErrRoleNotExist)
+		wantErr(t, "SetParents(missing parent)", rbac.SetParents(ctx, ids[0], cfg.ID(99)), gorbac.ErrRoleNotExist)
+		setParents(t, rbac, ids[0], ids[1])
+		setParents(t, rbac, ids[0], ids[1], ids[2])
+		parents, err := rbac.GetParents(ctx, ids[0])
+		if err != nil || !sameSet(parents, []R{ids[1], ids[2]}) {
+			t.Errorf("GetParents(%v) = %v, %v; parents should accumulate without duplicates", ids[0], parents, err)
+		}
+	})
+
+	t.Run("GetParents", func(t *testing.T) {
+		rbac, ids := cfg.fixture(t, 1)
+		parents, err := rbac.GetParents(ctx, ids[0])
+		if err != nil || len(parents) != 0 {
+			t.Errorf("GetParents(no parents) = %v, %v; want no parents and no error", parents, err)
+		}
+		_, err = rbac.GetParents(ctx, cfg.ID(99))
+		wantErr(t, "GetParents(missing)", err, gorbac.ErrRoleNotExist)
+	})
+
+	t.Run("RemoveParents", func(t *testing.T) {
+		rbac, ids := cfg.fixture(t, 3)
+		setParents(t, rbac, ids[0], ids[1], ids[2])
+		if err := rbac.RemoveParents(ctx, ids[0], ids[1]); err != nil {
+			t.Fatalf("RemoveParents: %v", err)
+		}
+		if parents, _ := rbac.GetParents(ctx, ids[0]); !sameSet(parents, []R{ids[2]}) {
+			t.Errorf("GetParents after RemoveParents = %v, want [%v]", parents, ids[2])
+		}
+		wantErr(t, "RemoveParents(missing role)", rbac.RemoveParents(ctx, cfg.ID(99), ids[0]), gorbac.ErrRoleNotExist)
+		wantErr(t, "RemoveParents(missing parent)", rbac.RemoveParents(ctx, ids[0], cfg.ID(99)), gorbac.ErrRoleNotExist)
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		rbac, ids := cfg.fixture(t, 3)
+		setParents(t, rbac, ids[0], ids[1])
+		setParents(t, rbac, ids[1], ids[2])
+		if err := rbac.Remove(ctx, ids[1]); err != nil {
+			t.Fatalf("Remove: %v", err)
+		}
+		wantErr(t, "Remove(removed)", rbac.Remove(ctx, ids[1]), gorbac.ErrRoleNotExist)
+		_, err := rbac.Get(ctx, ids[1])
+		wantErr(t, "Get(removed)", err, gorbac.ErrRoleNotExist)
+		_, err = rbac.GetParents(ctx, ids[1])
+		wantErr(t, "GetParents(removed)", err, gorbac.ErrRoleNotExist)
+		if parents, err := rbac.GetParents(ctx, ids[0]); err != nil || len(parents) != 0 {
+			t.Errorf("GetParents(child of removed) = %v, %v; Remove should drop the parent edges", parents, err)
+		}
+		if rbac.IsGranted(ctx, ids[0], cfg.perm(2)) {
+			t.Error("permissions should no longer be inherited through a removed role")
+		}
+		// a role added again with the same ID starts without parents
+		if err := rbac.Add(ctx, cfg.role(ids[1])); err != nil {
+			t.Fatalf("Add(re-added): %v", err)
+		}
+		if parents, _ := rbac.GetParents(ctx, ids[1]); len(parents) != 0 {
+			t.Errorf("GetParents(re-added) = %v, want none", parents)
+		}
+	})
+
+	t.Run("IsGranted", func(t *testing.T) {
+		rbac, ids := cfg.fixture(t, 3)
+		setParents(t, rbac, ids[0], ids[1])
+		setParents(t, rbac, ids[1], ids[2])
+		for _, tt := range []struct {
+			role int
+			perm int
+			want bool
+		}{
+			{0, 0, true}, {0, 1, true}, {0, 2, true},
+			{1, 0, false}, {2, 1, false},
+		} {
+			if got := rbac.IsGranted(ctx, ids[tt.role], cfg.perm(tt.perm)); got != tt.want {
+				t.Errorf("IsGranted(%v, %v) = %t, want %t", ids[tt.role], cfg.perm(tt.perm).ID(), got, tt.want)
+			}
+		}
+		if rbac.IsGranted(ctx, cfg.ID(99), cfg.perm(0)) {
+			t.Error("a missing role should not be granted anything")
+		}
+		if err := rbac.RemoveParents(ctx, ids[1], ids[2]); err != nil {
+			t.Fatal(err)
+		}
+		if rbac.IsGranted(ctx, ids[0], cfg.perm(2)) {
+			t.Error("IsGranted should follow RemoveParents")
+		}
+	})
+
+	t.Run("Helpers", func(t *testing.T) {
+		rbac, ids := cfg.fixture(t, 3)
+		setParents(t, rbac, ids[0], ids[1])
+		setParents(t, rbac, ids[1], ids[2])
+		visited := make(map[R]int)
+		err := gorbac.Walk(ctx, rbac, func(role gorbac.RoleOf[R, P], _ []R) error {
+			visited[role.ID()]++
+			return nil
+		})
+		if err != nil || len(visited) != len(ids) {
+			t.Errorf("Walk visited %v, %v; want every role once", visited, err)
+		}
+		if err := gorbac.InherCircle(ctx, rbac); err != nil {
+			t.Errorf("InherCircle(acyclic) = %v", err)
+		}
+		perms, err := gorbac.EffectivePermissions(ctx, rbac, ids[0])
+		if err != nil || len(perms) != 3 {
+			t.Errorf("EffectivePermissions = %v, %v; want 3 permissions", perms, err)
+		}
+		setParents(t, rbac, ids[2], ids[0])
+		wantErr(t, "InherCircle(cyclic)", gorbac.InherCircle(ctx, rbac), gorbac.ErrFoundCircle)
+	})
+
+	t.Run("Concurrency", func(t *testing.T) {
+		if cfg.SkipConcurrency {
+			t.Skip("Config.SkipConcurrency is set")
+		}
+		rbac, ids := cfg.fixture(t, 4)
+		var wg sync.WaitGroup
+		for i := range 4 {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for range 50 {
+					rbac.IsGranted(ctx, ids[0], cfg.perm(3))
+					_, _ = rbac.GetParents(ctx, ids[0])
+					_ = rbac.RoleIDs(ctx)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for range 50 {
+					_ = rbac.SetParents(ctx, ids[0], ids[1+i%3])
+					_ = rbac.RemoveParents(ctx, ids[0], ids[1+i%3])
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+// TestRole runs the Role conformance suite as subtests of `t`.
+// Config.NewRBAC is not used.
+func TestRole[R, P comparable](t *testing.T, cfg ConfigOf[R, P]) {
+	t.Helper()
+	cfg.check(t)
+	ctx := context.Background()
+	p0, p1, p2 := cfg.perm(0), cfg.perm(1), cfg.perm(2)
+
+	t.Run("ID", func(t *testing.T) {
+		if got := cfg.role(cfg.ID(0)).ID(); got != cfg.ID(0) {
+			t.Errorf("ID() = %v, want %v", got, cfg.ID(0))
+		}
+	})
+
+	t.Run("Permit", func(t *testing.T) {
+		role := cfg.role(cfg.ID(0))
+		if err := role.Assign(ctx, p0, p1); err != nil {
+			t.Fatalf("Assign: %v", err)
+		}
+		if !role.Permit(ctx, p0) || !role.Permit(ctx, p0, p1) {
+			t.Error("Permit should hold for assigned permissions")
+		}
+		if role.Permit(ctx, p2) || role.Permit(ctx, p0, p2) {
+			t.Error("Permit requires every permission to be assigned")
+		}
+		if role.Permit(ctx) {
+			t.Error("Permit() without permissions should be false")
+		}
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		role := cfg.role(cfg.ID(0))
+		if err := role.Assign(ctx, p0, p1); err != nil {
+			t.Fatalf("Assign: %v", err)
+		}
+		if err := role.Revoke(ctx, p0, p2); err != nil {
+			t.Errorf("Revoke(assigned and unassigned) = %v", err)
+		}
+		if role.Permit(ctx, p0) || !role.Permit(ctx, p1) {
+			t.Error("Revoke should remove exactly the revoked permissions")
+		}
+		if _, ok := role.Get(ctx, p0.ID()); ok {
+			t.Error("Get should not return a revoked permission")
+		}
+	})
+
+	t.Run("Permissions", func(t *testing.T) {
+		role := cfg.role(cfg.ID(0))
+		if err := role.Assign(ctx, p0, p1, p0); err != nil {
+			t.Fatalf("Assign: %v", err)
+		}
+		var ids []P
+		for _, p := range role.Permissions(ctx) {
+			ids = append(ids, p.ID())
+		}
+		if !sameSet(ids, []P{p0.ID(), p1.ID()}) {
+			t.Errorf("Permissions() = %v, want [%v %v] once each", ids, p0.ID(), p1.ID())
+		}
+		if m := role.PermissionsMap(ctx); len(m) != 2 || m[p1.ID()] == nil {
+			t.Errorf("PermissionsMap() = %v", m)
+		}
+		if p, ok := role.Get(ctx, p1.ID()); !ok || p.ID() != p1.ID() {
+			t.Errorf("Get(%v) = %v, %t", p1.ID(), p, ok)
+		}
+	})
+
+	t.Run("FilterPermissions", func(t *testing.T) {
+		role := cfg.role(cfg.ID(0))
+		fp := gorbac.NewFilterPermission(cfg.PermissionID(200), "true")
+		if err := role.Assign(ctx, p0, fp); err != nil {
+			t.Fatalf("Assign: %v", err)
+		}
+		if m := role.FilterPermissions(ctx); len(m) != 1 || m[fp.ID()] == nil {
+			t.Errorf("FilterPermissions() = %v, want only %v", m, fp.ID())
+		}
+		if !role.Permit(ctx, gorbac.NewPermission(fp.ID())) {
+			t.Error("a filter permission should be permitted by its ID")
+		}
+		if err := role.Revoke(ctx, fp); err != nil {
+			t.Fatalf("Revoke: %v", err)
+		}
+		if m := role.FilterPermissions(ctx); len(m) != 0 {
+			t.Errorf("FilterPermissions() after Revoke = %v", m)
+		}
+	})
+}
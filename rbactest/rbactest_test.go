@@ -0,0 +1,73 @@
+package rbactest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fy0/gorbac/v3"
+	"github.com/fy0/gorbac/v3/rbactest"
+)
+
+func TestStdRBAC(t *testing.T) {
+	rbactest.TestRBAC(t, rbactest.Config[string]{
+		NewRBAC: func(*testing.T) gorbac.RBAC[string] { return gorbac.New[string]() },
+		ID:      rbactest.StringID,
+	})
+}
+
+func TestStdRBACIntIDs(t *testing.T) {
+	rbactest.TestRBAC(t, rbactest.Config[int]{
+		NewRBAC: func(*testing.T) gorbac.RBAC[int] { return gorbac.New[int]() },
+		ID:      func(n int) int { return n },
+	})
+}
+
+func TestStdRBACDistinctIDs(t *testing.T) {
+	cfg := rbactest.ConfigOf[int64, string]{
+		NewRBAC:      func(*testing.T) gorbac.RBACOf[int64, string] { return gorbac.NewOf[int64, string]() },
+		ID:           func(n int) int64 { return int64(n) },
+		PermissionID: rbactest.StringID,
+	}
+	rbactest.TestRBAC(t, cfg)
+	rbactest.TestRole(t, cfg)
+}
+
+func TestCachedRBAC(t *testing.T) {
+	rbactest.TestRBAC(t, rbactest.Config[string]{
+		NewRBAC: func(*testing.T) gorbac.RBAC[string] { return gorbac.NewCached[string, string](gorbac.New[string]()) },
+		ID:      rbactest.StringID,
+	})
+}
+
+func TestLoggedRBAC(t *testing.T) {
+	rbactest.TestRBAC(t, rbactest.Config[string]{
+		NewRBAC: func(*testing.T) gorbac.RBAC[string] {
+			return gorbac.NewLogged[string, string](gorbac.New[string](), nil)
+		},
+		ID: rbactest.StringID,
+	})
+}
+
+func TestOverlayRBAC(t *testing.T) {
+	rbactest.TestRBAC(t, rbactest.Config[string]{
+		NewRBAC: func(*testing.T) gorbac.RBAC[string] { return gorbac.NewOverlay[string, string](gorbac.New[string]()) },
+		ID:      rbactest.StringID,
+	})
+}
+
+func TestVersionedRBAC(t *testing.T) {
+	rbactest.TestRBAC(t, rbactest.Config[string]{
+		NewRBAC: func(t *testing.T) gorbac.RBAC[string] {
+			v, err := gorbac.NewVersioned(context.Background(), gorbac.New[string]())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return v
+		},
+		ID: rbactest.StringID,
+	})
+}
+
+func TestStdRole(t *testing.T) {
+	rbactest.TestRole(t, rbactest.Config[string]{ID: rbactest.StringID})
+}
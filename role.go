@@ -5,68 +5,125 @@ import (
 	"sync"
 )
 
-// Role describes the role contract.
-type Role[T comparable] interface {
-	ID() T
-	Assign(context.Context, ...Permission[T]) error
-	Permit(context.Context, ...Permission[T]) bool
-	Revoke(context.Context, ...Permission[T]) error
-	Permissions(context.Context) []Permission[T]
-	PermissionsMap(context.Context) map[T]Permission[T]
-	Get(context.Context, T) (Permission[T], bool)
-	FilterPermissions(context.Context) map[T]Permission[T]
+// RoleOf describes the role contract.
+// R is the type of role ID and P is the type of permission ID.
+type RoleOf[R, P comparable] interface {
+	ID() R
+	Assign(context.Context, ...Permission[P]) error
+	Permit(context.Context, ...Permission[P]) bool
+	Revoke(context.Context, ...Permission[P]) error
+	Permissions(context.Context) []Permission[P]
+	PermissionsMap(context.Context) map[P]Permission[P]
+	Get(context.Context, P) (Permission[P], bool)
+	FilterPermissions(context.Context) map[P]Permission[P]
 }
 
-// Roles is a map
-type Roles[T comparable] map[T]Role[T]
+// Role describes the role contract where role IDs and permission IDs share
+// the type T.
+type Role[T comparable] = RoleOf[T, T]
+
+// RolesOf is a map
+type RolesOf[R, P comparable] map[R]RoleOf[R, P]
+
+// Roles is a map where role IDs and permission IDs share the type T.
+type Roles[T comparable] = RolesOf[T, T]
 
 // NewRole is the default role factory function.
 func NewRole[T comparable](id T) *StdRole[T] {
-	return &StdRole[T]{
+	return NewRoleOf[T, T](id)
+}
+
+// NewRoleOf is the default role factory function with distinct role and
+// permission ID types, e.g. `NewRoleOf[int64, string](1)`.
+func NewRoleOf[R, P comparable](id R) *StdRoleOf[R, P] {
+	return &StdRoleOf[R, P]{
 		mutex:             new(sync.RWMutex),
 		IDValue:           id,
-		permissions:       make(Permissions[T]),
-		filterPermissions: make(map[T]Permission[T]),
+		permissions:       make(Permissions[P]),
+		filterPermissions: make(map[P]Permission[P]),
+		indexes:           make(map[string]MatcherIndex[P]),
+		unindexed:         make(map[P]Permission[P]),
 	}
 }
 
-// StdRole is the default role implementation.
+// StdRoleOf is the default role implementation.
 // You can embed this struct into your own role implementation.
-// T is the type of ID.
-type StdRole[T comparable] struct {
+// R is the type of role ID and P is the type of permission ID.
+type StdRoleOf[R, P comparable] struct {
 	mutex *sync.RWMutex
 	// ID is the serialisable identity of role
-	IDValue           T `json:"id"`
-	permissions       Permissions[T]
-	filterPermissions map[T]Permission[T]
+	IDValue           R `json:"id"`
+	permissions       Permissions[P]
+	filterPermissions map[P]Permission[P]
+	implications      *Implications[P]
+	// indexes and unindexed partition permissions for the Permit slow path
+	indexes   map[string]MatcherIndex[P]
+	unindexed map[P]Permission[P]
 }
 
-func (role *StdRole[T]) init() {
+// StdRole is the default role implementation where role IDs and permission
+// IDs share the type T.
+type StdRole[T comparable] = StdRoleOf[T, T]
+
+func (role *StdRoleOf[R, P]) init() {
 	if role.mutex == nil {
 		role.mutex = new(sync.RWMutex)
 	}
 	if role.permissions == nil {
-		role.permissions = make(Permissions[T])
+		role.permissions = make(Permissions[P])
 	}
 	if role.filterPermissions == nil {
-		role.filterPermissions = make(map[T]Permission[T])
+		role.filterPermissions = make(map[P]Permission[P])
+	}
+	if role.indexes == nil {
+		role.indexes = make(map[string]MatcherIndex[P])
+	}
+	if role.unindexed == nil {
+		role.unindexed = make(map[P]Permission[P])
+	}
+}
+
+func (role *StdRoleOf[R, P]) index(p Permission[P]) {
+	key, newIndex, ok := indexOf(p)
+	if !ok {
+		role.unindexed[p.ID()] = p
+		return
+	}
+	idx, ok := role.indexes[key]
+	if !ok {
+		idx = newIndex()
+		role.indexes[key] = idx
+	}
+	idx.Insert(p)
+}
+
+func (role *StdRoleOf[R, P]) unindex(p Permission[P]) {
+	delete(role.unindexed, p.ID())
+	if key, _, ok := indexOf(p); ok {
+		if idx, ok := role.indexes[key]; ok {
+			idx.Delete(p)
+		}
 	}
 }
 
 // ID returns the role ID.
-func (role *StdRole[T]) ID() T {
+func (role *StdRoleOf[R, P]) ID() R {
 	return role.IDValue
 }
 
 // Assign permissions to the role.
-func (role *StdRole[T]) Assign(_ context.Context, perms ...Permission[T]) error {
+func (role *StdRoleOf[R, P]) Assign(_ context.Context, perms ...Permission[P]) error {
 	if len(perms) == 0 {
 		return nil
 	}
 	role.init()
 	role.mutex.Lock()
 	for _, p := range perms {
+		if old, ok := role.permissions[p.ID()]; ok {
+			role.unindex(old)
+		}
 		role.permissions[p.ID()] = p
+		role.index(p)
 		if _, ok := p.(interface {
 			CEL() (string, error)
 		}); ok {
@@ -79,12 +136,30 @@ func (role *StdRole[T]) Assign(_ context.Context, perms ...Permission[T]) error
 	return nil
 }
 
+// SetImplications binds a permission implication registry to the role.
+// Permit treats a permission as held when the role holds a stronger one.
+// Passing nil disables implications.
+func (role *StdRoleOf[R, P]) SetImplications(imp *Implications[P]) {
+	role.init()
+	role.mutex.Lock()
+	role.implications = imp
+	role.mutex.Unlock()
+}
+
+// Implications returns the bound implication registry, or nil.
+func (role *StdRoleOf[R, P]) Implications() *Implications[P] {
+	role.init()
+	role.mutex.RLock()
+	defer role.mutex.RUnlock()
+	return role.implications
+}
+
 // Permit returns true if the role has all specified permissions.
-func (role *StdRole[T]) Permit(_ context.Context, perms ...Permission[T]) bool {
+func (role *StdRoleOf[R, P]) Permit(ctx context.Context, perms ...Permission[P]) bool {
 	if len(perms) == 0 {
 		return false
 	}
-	var zero Permission[T]
+	var zero Permission[P]
 	role.init()
 	role.mutex.RLock()
 	for _, p := range perms {
@@ -96,12 +171,29 @@ func (role *StdRole[T]) Permit(_ context.Context, perms ...Permission[T]) bool {
 		// Fast path: permission IDs are used as map keys for exact matches.
 		//
 		// This preserves existing behavior for layered / custom matching because
-		// we still fall back to scanning the full permission set when needed.
+		// we still fall back to the matcher indexes and scanning the permissions
+		// which have no index when needed.
 		if rp, exists := role.permissions[p.ID()]; exists && rp.Match(p) {
 			matched = true
 		} else {
-			for _, rp := range role.permissions {
-				if rp.Match(p) {
+			for _, idx := range role.indexes {
+				if idx.Match(p) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				for _, rp := range role.unindexed {
+					if rp.Match(p) {
+						matched = true
+						break
+					}
+				}
+			}
+		}
+		if !matched && role.implications != nil {
+			for _, id := range role.implications.Stronger(ctx, p.ID()) {
+				if _, exists := role.permissions[id]; exists {
 					matched = true
 					break
 				}
@@ -117,13 +209,16 @@ func (role *StdRole[T]) Permit(_ context.Context, perms ...Permission[T]) bool {
 }
 
 // Revoke the specific permissions.
-func (role *StdRole[T]) Revoke(_ context.Context, perms ...Permission[T]) error {
+func (role *StdRoleOf[R, P]) Revoke(_ context.Context, perms ...Permission[P]) error {
 	if len(perms) == 0 {
 		return nil
 	}
 	role.init()
 	role.mutex.Lock()
 	for _, p := range perms {
+		if old, ok := role.permissions[p.ID()]; ok {
+			role.unindex(old)
+		}
 		delete(role.permissions, p.ID())
 		delete(role.filterPermissions, p.ID())
 	}
@@ -132,10 +227,10 @@ func (role *StdRole[T]) Revoke(_ context.Context, perms ...Permission[T]) error
 }
 
 // Permissions returns all permissions into a slice.
-func (role *StdRole[T]) Permissions(_ context.Context) []Permission[T] {
+func (role *StdRoleOf[R, P]) Permissions(_ context.Context) []Permission[P] {
 	role.init()
 	role.mutex.RLock()
-	result := make([]Permission[T], 0, len(role.permissions))
+	result := make([]Permission[P], 0, len(role.permissions))
 	for _, p := range role.permissions {
 		result = append(result, p)
 	}
@@ -144,13 +239,13 @@ func (role *StdRole[T]) Permissions(_ context.Context) []Permission[T] {
 }
 
 // PermissionsMap returns a raw ref of permissions keyed by ID.
-func (role *StdRole[T]) PermissionsMap(_ context.Context) map[T]Permission[T] {
+func (role *StdRoleOf[R, P]) PermissionsMap(_ context.Context) map[P]Permission[P] {
 	role.init()
 	return role.permissions
 }
 
 // Get returns a permission by ID.
-func (role *StdRole[T]) Get(_ context.Context, id T) (Permission[T], bool) {
+func (role *StdRoleOf[R, P]) Get(_ context.Context, id P) (Permission[P], bool) {
 	role.init()
 	role.mutex.RLock()
 	p, ok := role.permissions[id]
@@ -159,7 +254,7 @@ func (role *StdRole[T]) Get(_ context.Context, id T) (Permission[T], bool) {
 }
 
 // FilterPermissions returns a raw ref of CEL-carrying permissions keyed by ID.
-func (role *StdRole[T]) FilterPermissions(_ context.Context) map[T]Permission[T] {
+func (role *StdRoleOf[R, P]) FilterPermissions(_ context.Context) map[P]Permission[P] {
 	role.init()
 	return role.filterPermissions
 }
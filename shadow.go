@@ -0,0 +1,216 @@
+package gorbac
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultShadowQueueSize is the number of pending candidate checks kept by
+// ShadowRBACOf unless WithShadowQueueSize says otherwise.
+const DefaultShadowQueueSize = 1024
+
+// Mismatch is a decision on which the candidate of a ShadowRBACOf disagrees
+// with the primary.
+type Mismatch[R, P comparable] struct {
+	Time       time.Time
+	Subject    string
+	Role       R
+	Permission Permission[P]
+	Primary    bool
+	Candidate  bool
+	// PrimaryPath and CandidatePath explain a granted decision with GrantPath,
+	// computed when the mismatch is reported.
+	PrimaryPath   []R
+	CandidatePath []R
+	// Err is the error of the candidate check, e.g. from a Checker.
+	Err error
+}
+
+type shadowConfig struct {
+	queueSize  int
+	sampleRate float64
+}
+
+// ShadowOption customizes ShadowRBACOf construction.
+type ShadowOption func(*shadowConfig)
+
+// WithShadowQueueSize bounds the number of pending candidate checks.
+// Checks are dropped instead of blocking when the queue is full.
+func WithShadowQueueSize(size int) ShadowOption {
+	return func(cfg *shadowConfig) {
+		if size > 0 {
+			cfg.queueSize = size
+		}
+	}
+}
+
+// WithShadowSampleRate evaluates the candidate for the given fraction of
+// decisions, between 0 and 1. Every decision is evaluated by default.
+func WithShadowSampleRate(rate float64) ShadowOption {
+	return func(cfg *shadowConfig) {
+		cfg.sampleRate = min(max(rate, 0), 1)
+	}
+}
+
+type shadowCheck[R, P comparable] struct {
+	ctx     context.Context
+	time    time.Time
+	id      R
+	p       Permission[P]
+	granted bool
+}
+
+// ShadowRBACOf runs a candidate RBAC alongside a primary one.
+//
+// Every operation is served by the primary. Decisions are additionally
+// evaluated against the candidate on a background goroutine, and
+// disagreements are reported to the mismatch callback; the candidate never
+// changes an outcome. Mutations only apply to the primary: the candidate is
+// typically a separately loaded policy under evaluation.
+type ShadowRBACOf[R, P comparable] struct {
+	RBACOf[R, P]
+	candidate  RBACOf[R, P]
+	onMismatch func(context.Context, Mismatch[R, P])
+	sampleRate float64
+
+	queue chan shadowCheck[R, P]
+	done  chan struct{}
+	once  sync.Once
+	mutex sync.RWMutex
+
+	closed     bool
+	compared   atomic.Uint64
+	mismatches atomic.Uint64
+	dropped    atomic.Uint64
+}
+
+// ShadowRBAC is a ShadowRBACOf where role IDs and permission IDs share the type T.
+type ShadowRBAC[T comparable] = ShadowRBACOf[T, T]
+
+// NewShadow serves decisions from `primary` and reports to `onMismatch`
+// whenever `candidate` decides differently. Call Close to stop it.
+func NewShadow[R, P comparable](primary, candidate RBACOf[R, P],
+	onMismatch func(context.Context, Mismatch[R, P]), opts ...ShadowOption) *ShadowRBACOf[R, P] {
+	cfg := &shadowConfig{queueSize: DefaultShadowQueueSize, sampleRate: 1}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(cfg)
+	}
+	s := &ShadowRBACOf[R, P]{
+		RBACOf:     primary,
+		candidate:  candidate,
+		onMismatch: onMismatch,
+		sampleRate: cfg.sampleRate,
+		queue:      make(chan shadowCheck[R, P], cfg.queueSize),
+		done:       make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		for c := range s.queue {
+			s.compare(c)
+		}
+	}()
+	return s
+}
+
+// Candidate returns the RBAC under evaluation.
+func (s *ShadowRBACOf[R, P]) Candidate() RBACOf[R, P] {
+	return s.candidate
+}
+
+// IsGranted tests if the role `id` has permission `p` with the primary and
+// schedules the same check on the candidate.
+func (s *ShadowRBACOf[R, P]) IsGranted(ctx context.Context, id R, p Permission[P]) bool {
+	ok := s.RBACOf.IsGranted(ctx, id, p)
+	s.enqueue(ctx, id, p, ok)
+	return ok
+}
+
+// Check tests if the role `id` has permission `p` with the primary and
+// schedules the same check on the candidate unless an error occurred.
+func (s *ShadowRBACOf[R, P]) Check(ctx context.Context, id R, p Permission[P]) (bool, error) {
+	ok, err := Check(ctx, s.RBACOf, id, p)
+	if err == nil {
+		s.enqueue(ctx, id, p, ok)
+	}
+	return ok, err
+}
+
+func (s *ShadowRBACOf[R, P]) enqueue(ctx context.Context, id R, p Permission[P], granted bool) {
+	if s.sampleRate < 1 && rand.Float64() >= s.sampleRate {
+		return
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.closed {
+		s.dropped.Add(1)
+		return
+	}
+	select {
+	case s.queue <- shadowCheck[R, P]{ctx: context.WithoutCancel(ctx), time: time.Now(), id: id, p: p, granted: granted}:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *ShadowRBACOf[R, P]) compare(c shadowCheck[R, P]) {
+	candidate, err := Check(c.ctx, s.candidate, c.id, c.p)
+	s.compared.Add(1)
+	if err == nil && candidate == c.granted {
+		return
+	}
+	s.mismatches.Add(1)
+	if s.onMismatch == nil {
+		return
+	}
+	m := Mismatch[R, P]{
+		Time:       c.time,
+		Subject:    subjectString(c.ctx),
+		Role:       c.id,
+		Permission: c.p,
+		Primary:    c.granted,
+		Candidate:  candidate,
+		Err:        err,
+	}
+	if c.granted {
+		m.PrimaryPath, _ = GrantPath(c.ctx, s.RBACOf, c.id, c.p)
+	}
+	if candidate {
+		m.CandidatePath, _ = GrantPath(c.ctx, s.candidate, c.id, c.p)
+	}
+	s.onMismatch(c.ctx, m)
+}
+
+// Compared returns the number of candidate checks performed so far.
+func (s *ShadowRBACOf[R, P]) Compared() uint64 {
+	return s.compared.Load()
+}
+
+// Mismatches returns the number of disagreements found so far.
+func (s *ShadowRBACOf[R, P]) Mismatches() uint64 {
+	return s.mismatches.Load()
+}
+
+// Dropped returns the number of candidate checks dropped because the queue
+// was full or the wrapper closed.
+func (s *ShadowRBACOf[R, P]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close evaluates the pending candidate checks and stops the background
+// goroutine. The primary keeps serving decisions afterwards.
+func (s *ShadowRBACOf[R, P]) Close() error {
+	s.once.Do(func() {
+		s.mutex.Lock()
+		s.closed = true
+		close(s.queue)
+		s.mutex.Unlock()
+	})
+	<-s.done
+	return nil
+}
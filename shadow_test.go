@@ -0,0 +1,105 @@
+package gorbac
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestShadowRBAC(t *testing.T) {
+	ctx := WithSubject(context.Background(), "bob")
+	primary := New[string]()
+	candidate := New[string]()
+	for _, rbac := range []*StdRBAC[string]{primary, candidate} {
+		rA := NewRole("role-a")
+		assert(t, rA.Assign(ctx, pA))
+		assert(t, rbac.Add(ctx, rA))
+		assert(t, rbac.Add(ctx, NewRole("role-b")))
+	}
+	// the candidate lets role-b inherit role-a
+	assert(t, candidate.SetParents(ctx, "role-b", "role-a"))
+
+	var mutex sync.Mutex
+	var mismatches []Mismatch[string, string]
+	shadow := NewShadow[string, string](primary, candidate, func(_ context.Context, m Mismatch[string, string]) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		mismatches = append(mismatches, m)
+	})
+	var _ RBAC[string] = shadow
+
+	if !shadow.IsGranted(ctx, "role-a", pA) {
+		t.Fatal("role-a should be granted permission-a")
+	}
+	if shadow.IsGranted(ctx, "role-b", pA) {
+		t.Fatal("the primary decision should be returned")
+	}
+	if ok, err := shadow.Check(ctx, "role-b", pB); ok || err != nil {
+		t.Fatalf("unexpected check result %v, %v", ok, err)
+	}
+	assert(t, shadow.Close())
+
+	if shadow.Compared() != 3 || shadow.Mismatches() != 1 || shadow.Dropped() != 0 {
+		t.Fatalf("unexpected counters %d, %d, %d", shadow.Compared(), shadow.Mismatches(), shadow.Dropped())
+	}
+	if len(mismatches) != 1 {
+		t.Fatalf("1 mismatch expected, but %d got", len(mismatches))
+	}
+	m := mismatches[0]
+	if m.Role != "role-b" || m.Permission.ID() != pA.ID() || m.Primary || !m.Candidate || m.Subject != "bob" {
+		t.Fatalf("unexpected mismatch %+v", m)
+	}
+	if m.PrimaryPath != nil || !slices.Equal(m.CandidatePath, []string{"role-b", "role-a"}) {
+		t.Fatalf("unexpected explanation %v, %v", m.PrimaryPath, m.CandidatePath)
+	}
+
+	// mutations only apply to the primary
+	assert(t, shadow.Add(ctx, NewRole("role-c")))
+	if _, err := primary.Get(ctx, "role-c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := candidate.Get(ctx, "role-c"); err != ErrRoleNotExist {
+		t.Fatalf("%s expected, but %v got", ErrRoleNotExist, err)
+	}
+	if !shadow.IsGranted(ctx, "role-a", pA) || shadow.Dropped() != 1 {
+		t.Fatal("checks after Close should be dropped")
+	}
+}
+
+func TestShadowRBACDrop(t *testing.T) {
+	ctx := context.Background()
+	primary := New[string]()
+	candidate := New[string]()
+	rA := NewRole("role-a")
+	assert(t, rA.Assign(ctx, pA))
+	assert(t, candidate.Add(ctx, rA))
+	assert(t, primary.Add(ctx, NewRole("role-a")))
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	shadow := NewShadow[string, string](primary, candidate, func(context.Context, Mismatch[string, string]) {
+		started <- struct{}{}
+		<-release
+	}, WithShadowQueueSize(1))
+
+	shadow.IsGranted(ctx, "role-a", pA)
+	<-started
+	shadow.IsGranted(ctx, "role-a", pA)
+	shadow.IsGranted(ctx, "role-a", pA)
+	if shadow.Dropped() != 1 {
+		t.Fatalf("1 dropped check expected, but %d got", shadow.Dropped())
+	}
+	close(release)
+	assert(t, shadow.Close())
+	if shadow.Compared() != 2 || shadow.Mismatches() != 2 {
+		t.Fatalf("unexpected counters %d, %d", shadow.Compared(), shadow.Mismatches())
+	}
+
+	sampled := NewShadow[string, string](primary, candidate, nil, WithShadowSampleRate(0))
+	sampled.IsGranted(ctx, "role-a", pA)
+	assert(t, sampled.Close())
+	if sampled.Compared() != 0 {
+		t.Fatal("no check should be sampled")
+	}
+}